@@ -6,7 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // @title User Service REST API
@@ -31,25 +36,51 @@ const (
 )
 
 type API struct {
-	cfg        Config
-	basePath   string
-	httpClient *http.Client
+	cfg         Config
+	basePath    string
+	httpClient  *http.Client
+	retryPolicy *RetryPolicy
+
+	middlewares    []Middleware
+	requestEditors []RequestEditor
+
+	logger    Logger
+	logBodies bool
+
+	breaker *circuitBreaker
+	limiter *rateLimiter
+	hedger  *hedger
+
+	balancing LoadBalancingPolicy
+	balancer  *balancer
+
+	cache        Cache
+	staleIfError bool
+	fetches      fetchTracker
+
+	modifyAttempts int
 }
 
 type Config struct {
 	URL string
+	// URLs optionally provides several URLs the service is available at, like one for each zone, replacing URL.
+	// Requests are balanced among them according to the LoadBalancingPolicy, see WithLoadBalancing.
+	URLs []string
 }
 
 // New creates a new API client
 func New(config Config, options ...Option) *API {
 	api := &API{
-		cfg:        config,
-		basePath:   defaultBasePath,
-		httpClient: http.DefaultClient,
+		cfg:            config,
+		basePath:       defaultBasePath,
+		httpClient:     http.DefaultClient,
+		modifyAttempts: defaultModifyAttempts,
+		balancing:      DefaultLoadBalancingPolicy(),
 	}
 	for _, opt := range options {
 		opt(api)
 	}
+	api.balancer = newBalancer(config, api.balancing)
 	return api
 }
 
@@ -79,13 +110,17 @@ func WithHTTPClient(client *http.Client) Option {
 // @Produce json
 // @Success 201 {object} User
 // @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
 // @Router /users [post]
 func (a *API) CreateUser(ctx context.Context, user *User) (*User, error) {
 	if user == nil {
 		return nil, fmt.Errorf("user can't be nil")
 	}
-	resp, err := a.doRequest(ctx, http.MethodPost, usersPath, user)
+	resp, err := a.doRequest(ctx, opPostUser, usersPath, nil, nil, user)
 	if err != nil {
 		return nil, err
 	}
@@ -96,8 +131,11 @@ func (a *API) CreateUser(ctx context.Context, user *User) (*User, error) {
 	case http.StatusBadRequest,
 		http.StatusInternalServerError:
 		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return nil, a.retryAfterError(resp)
 	default:
-		return nil, fmt.Errorf("received unexpected status code %d", resp.StatusCode)
+		return nil, a.unexpectedStatusError(resp)
 	}
 }
 
@@ -110,16 +148,23 @@ func (a *API) CreateUser(ctx context.Context, user *User) (*User, error) {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} User
+// @Header 200 {string} ETag "Version of the user, its updated_at enclosed in double quotes, as a strong validator"
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse "If UpdatedAt field doesn't match"
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
 // @Router /users/{id} [put]
 func (a *API) UpdateUser(ctx context.Context, user *User) (*User, error) {
 	if user == nil {
 		return nil, fmt.Errorf("user can't be nil")
 	}
-	resp, err := a.doRequest(ctx, http.MethodPut, fmt.Sprintf("%s/%s", usersPath, user.ID), user)
+	// invalidated after the request, so users retrieved concurrently are not cached
+	defer a.uncacheUser(user.ID)
+	resp, err := a.doRequest(ctx, opPutUser, fmt.Sprintf("%s/%s", usersPath, user.ID), nil, nil, user)
 	if err != nil {
 		return nil, err
 	}
@@ -132,8 +177,11 @@ func (a *API) UpdateUser(ctx context.Context, user *User) (*User, error) {
 		http.StatusNotFound,
 		http.StatusInternalServerError:
 		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return nil, a.retryAfterError(resp)
 	default:
-		return nil, fmt.Errorf("received unexpected status code %d", resp.StatusCode)
+		return nil, a.unexpectedStatusError(resp)
 	}
 }
 
@@ -143,10 +191,15 @@ func (a *API) UpdateUser(ctx context.Context, user *User) (*User, error) {
 // @Param id path string true "User ID"
 // @Success 204
 // @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
 // @Router /users/{id} [delete]
 func (a *API) DeleteUser(ctx context.Context, id string) error {
-	resp, err := a.doRequest(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", usersPath, id), nil)
+	defer a.uncacheUser(id)
+	resp, err := a.doRequest(ctx, opDeleteUser, fmt.Sprintf("%s/%s", usersPath, id), nil, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -157,100 +210,373 @@ func (a *API) DeleteUser(ctx context.Context, id string) error {
 	case http.StatusNotFound,
 		http.StatusInternalServerError:
 		return a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return a.retryAfterError(resp)
 	default:
-		return fmt.Errorf("received unexpected status code %d", resp.StatusCode)
+		return a.unexpectedStatusError(resp)
 	}
 }
 
 // GetUser retrieves a user by its ID.
+// If the API was configured WithCache, the cached user is revalidated with the service.
 // @Summary Retrieve a user by its ID.
+// @Description The `ETag` header can be provided as `If-None-Match` to respond 304 if the user was not modified.
 // @ID get-user
 // @Produce json
 // @Param id path string true "User ID"
+// @Param If-None-Match header string false "ETag of the user previously retrieved"
 // @Success 200 {object} User
+// @Header 200 {string} ETag "Version of the user, its updated_at enclosed in double quotes, as a strong validator"
+// @Success 304 "If the ETag provided as If-None-Match matches"
 // @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
 // @Router /users/{id} [get]
 func (a *API) GetUser(ctx context.Context, id string) (*User, error) {
-	resp, err := a.doRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%s", usersPath, id), nil)
+	var generation uint64
+	if a.cache != nil {
+		generation = a.fetches.start(id)
+	}
+	cached, isCached := a.cachedUser(id)
+	var header http.Header
+	if isCached {
+		header = http.Header{"If-None-Match": {cached.ETag}}
+	}
+	resp, err := a.doRequest(ctx, opGetUser, fmt.Sprintf("%s/%s", usersPath, id), nil, header, nil)
+	// users updated or deleted meanwhile through this API are neither cached nor returned stale
+	current := a.cache == nil || a.fetches.finish(id, generation)
+	stale := isCached && a.staleIfError && current
 	if err != nil {
+		if stale && ctx.Err() == nil {
+			return cached.user(), nil
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if stale && resp.StatusCode >= http.StatusInternalServerError {
+		return cached.user(), nil
+	}
 	switch resp.StatusCode {
 	case http.StatusOK:
-		return a.unmarshalUserResponse(resp)
-	case http.StatusNotFound,
-		http.StatusInternalServerError:
+		user, err := a.unmarshalUserResponse(resp)
+		if err == nil && current {
+			a.cacheUser(user, resp.Header.Get("ETag"))
+		}
+		return user, err
+	case http.StatusNotModified:
+		if !isCached {
+			return nil, a.unexpectedStatusError(resp)
+		}
+		return cached.user(), nil
+	case http.StatusNotFound:
+		a.uncacheUser(id)
 		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusInternalServerError:
+		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return nil, a.retryAfterError(resp)
 	default:
-		return nil, fmt.Errorf("received unexpected status code %d", resp.StatusCode)
+		return nil, a.unexpectedStatusError(resp)
 	}
 }
 
 // ListUsers lists existing users with optional filters.
+// Only the first page is returned when params.Limit is set, use ListUsersPage or IterateUsers to list the following ones.
+// StreamUsers lists all of them without keeping them in memory.
 // @Summary List users.
-// @Description List users, can be filtered by country code.
+// @Description List users, can be filtered by country codes, IDs, email, names, a free-text query, or creation and update times.
 // @Description This operation does not return the PasswordHash and PasswordSalt fields for security reasons.
+// @Description Results can be paginated using the `limit` parameter: when there are more results,
+// @Description the `Link` header contains the URL of the next page with `rel="next"`, including the `cursor` parameter.
+// @Description Users are sorted by `id` unless `sort` is provided, and ties are sorted by `id` too.
 // @ID list-users
 // @Produce json
-// @Param country query string false "filter by country code"
+// @Param country query []string false "filter by country codes, can be provided several times"
+// @Param id query []string false "filter by user IDs, can be provided several times"
+// @Param email query string false "filter by email"
+// @Param name query string false "filter by nickname"
+// @Param name_prefix query string false "filter by nickname prefix"
+// @Param first_name query string false "filter by first name"
+// @Param last_name query string false "filter by last name"
+// @Param q query string false "free-text search, case-insensitive, on the nickname, first name, last name and email"
+// @Param created_after query string false "filter by users created after the RFC3339 time" format(date-time)
+// @Param created_before query string false "filter by users created before the RFC3339 time" format(date-time)
+// @Param updated_after query string false "filter by users updated after the RFC3339 time" format(date-time)
+// @Param sort query string false "comma separated fields to sort by, prefixed with - for descending order, like created_at,-name"
+// @Param fields query string false "comma separated fields to respond, like id,name,country, the rest are omitted"
+// @Param limit query int false "maximum number of users to return, the service can return fewer"
+// @Param cursor query string false "opaque cursor to retrieve the next page, obtained from the Link header"
 // @Success 200 {array} User
+// @Header 200 {string} Link "URL of the next page, with rel next, absent on the last page"
 // @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
 // @Router /users [get]
 func (a *API) ListUsers(ctx context.Context, params ListUsersParams) ([]User, error) {
-	req, err := a.request(ctx, http.MethodGet, usersPath, nil)
+	page, err := a.ListUsersPage(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	return page.Users, nil
+}
 
-	if params.Country != "" {
-		query := req.URL.Query()
-		query.Add("country", params.Country)
-		req.URL.RawQuery = query.Encode()
+// ListUsersParams configures the terms of user listing.
+type ListUsersParams struct {
+	// Country optionally filters the list by country code.
+	Country string
+	// Countries optionally filters the list by several country codes, Country is added to them if set.
+	Countries []string
+	// Limit optionally limits the amount of users returned in a single page.
+	Limit int
+	// Cursor optionally requests the page following the one it was obtained from.
+	Cursor string
+	// IDs optionally filters the list by user IDs.
+	IDs []string
+	// Email optionally filters the list by email.
+	Email string
+	// Name optionally filters the list by nickname.
+	Name string
+	// NamePrefix optionally filters the list by the beginning of the nickname.
+	NamePrefix string
+	// FirstName optionally filters the list by first name.
+	FirstName string
+	// LastName optionally filters the list by last name.
+	LastName string
+	// Query optionally filters the list by a case-insensitive free-text search
+	// on the nickname, first name, last name and email.
+	Query string
+	// CreatedAfter and CreatedBefore optionally filter the list by creation time, both are exclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// UpdatedAfter optionally filters the list by users updated after the given time.
+	UpdatedAfter time.Time
+	// Sort optionally sorts the list by several fields, the first one has the highest priority.
+	Sort []SortKey
+	// Fields optionally limits the fields provided for each user to the ones with these JSON names, like "id".
+	// The rest of the fields of the users listed are left empty.
+	Fields []string
+}
+
+// SortKey is a field to sort the user listing by.
+type SortKey struct {
+	// Field is the JSON name of the field, like "created_at".
+	Field string
+	// Descending reverses the order of the field.
+	Descending bool
+}
+
+func (k SortKey) String() string {
+	if k.Descending {
+		return "-" + k.Field
 	}
+	return k.Field
+}
 
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("can't perform http request: %w", err)
+// validate checks that the time ranges of the params are not empty, and that the fields can be encoded.
+func (p ListUsersParams) validate() error {
+	if !p.CreatedAfter.IsZero() && !p.CreatedBefore.IsZero() && !p.CreatedAfter.Before(p.CreatedBefore) {
+		return fmt.Errorf("CreatedAfter %s should be before CreatedBefore %s", p.CreatedAfter.Format(time.RFC3339Nano), p.CreatedBefore.Format(time.RFC3339Nano))
 	}
-	defer resp.Body.Close()
+	for _, key := range p.Sort {
+		if key.Field == "" || strings.Contains(key.Field, ",") {
+			return fmt.Errorf("invalid sort field %q", key.Field)
+		}
+	}
+	for _, field := range p.Fields {
+		if field == "" || strings.Contains(field, ",") {
+			return fmt.Errorf("invalid field %q", field)
+		}
+	}
+	return nil
+}
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		var users []User
-		if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
-			return nil, fmt.Errorf("response was %d, however can't unmarshal users JSON: %w", resp.StatusCode, err)
+func (p ListUsersParams) query() url.Values {
+	query := url.Values{}
+	if p.Country != "" {
+		query.Add("country", p.Country)
+	}
+	for _, country := range p.Countries {
+		query.Add("country", country)
+	}
+	if p.Limit > 0 {
+		query.Add("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		query.Add("cursor", p.Cursor)
+	}
+	for _, id := range p.IDs {
+		query.Add("id", id)
+	}
+	for key, value := range map[string]string{
+		"email":       p.Email,
+		"name":        p.Name,
+		"name_prefix": p.NamePrefix,
+		"first_name":  p.FirstName,
+		"last_name":   p.LastName,
+		"q":           p.Query,
+	} {
+		if value != "" {
+			query.Add(key, value)
+		}
+	}
+	for key, value := range map[string]time.Time{
+		"created_after":  p.CreatedAfter,
+		"created_before": p.CreatedBefore,
+		"updated_after":  p.UpdatedAfter,
+	} {
+		if !value.IsZero() {
+			query.Add(key, value.UTC().Format(time.RFC3339Nano))
 		}
-		return users, nil
-	case http.StatusBadRequest,
-		http.StatusInternalServerError:
-		return nil, a.unmarshalErrorResponse(resp)
-	default:
-		return nil, fmt.Errorf("received unexpected status code %d", resp.StatusCode)
 	}
+	if len(p.Sort) > 0 {
+		keys := make([]string, len(p.Sort))
+		for i, key := range p.Sort {
+			keys[i] = key.String()
+		}
+		query.Add("sort", strings.Join(keys, ","))
+	}
+	if len(p.Fields) > 0 {
+		query.Add("fields", strings.Join(p.Fields, ","))
+	}
+	return query
 }
 
-// ListUsersParams configures the terms of user listing.
-type ListUsersParams struct {
-	// Country optionally filters the list by country code.
-	Country string
+// Operation describes one of the operations documented in the contract.
+type Operation struct {
+	// ID is the swagger operation ID, like "get-user".
+	ID     string
+	Method string
+	// Idempotent operations can be safely performed more than once.
+	Idempotent bool
+
+	// statuses are the status codes documented for the operation, besides the commonStatuses.
+	statuses []int
 }
 
-func (a *API) doRequest(ctx context.Context, method, path string, user interface{}) (*http.Response, error) {
-	req, err := a.request(ctx, method, path, user)
-	if err != nil {
-		return nil, err
+// documents reports whether the status code is documented for the operation.
+func (op Operation) documents(statusCode int) bool {
+	for _, statuses := range [][]int{op.statuses, commonStatuses} {
+		for _, code := range statuses {
+			if code == statusCode {
+				return true
+			}
+		}
 	}
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("can't perform http request: %w", err)
+	return false
+}
+
+var (
+	opPostUser = Operation{ID: "post-user", Method: http.MethodPost,
+		statuses: []int{http.StatusCreated, http.StatusBadRequest}}
+	opPutUser = Operation{ID: "put-user", Method: http.MethodPut, Idempotent: true,
+		statuses: []int{http.StatusOK, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}}
+	opPatchUser = Operation{ID: "patch-user", Method: http.MethodPatch, Idempotent: true,
+		statuses: []int{http.StatusOK, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusUnsupportedMediaType}}
+	opDeleteUser = Operation{ID: "delete-user", Method: http.MethodDelete, Idempotent: true,
+		statuses: []int{http.StatusNoContent, http.StatusNotFound}}
+	opGetUser = Operation{ID: "get-user", Method: http.MethodGet, Idempotent: true,
+		statuses: []int{http.StatusOK, http.StatusNotModified, http.StatusNotFound}}
+	opListUsers = Operation{ID: "list-users", Method: http.MethodGet, Idempotent: true,
+		statuses: []int{http.StatusOK, http.StatusBadRequest}}
+	opBatchGetUsers = Operation{ID: "batch-get-users", Method: http.MethodPost, Idempotent: true,
+		statuses: []int{http.StatusOK, http.StatusBadRequest}}
+	opBatchCreateUsers = Operation{ID: "batch-create-users", Method: http.MethodPost,
+		statuses: []int{http.StatusOK, http.StatusBadRequest}}
+	opBatchDeleteUsers = Operation{ID: "batch-delete-users", Method: http.MethodPost, Idempotent: true,
+		statuses: []int{http.StatusOK, http.StatusBadRequest}}
+)
+
+// commonStatuses are the status codes documented for all the operations.
+var commonStatuses = []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+// doRequest performs the operation through the configured middlewares.
+func (a *API) doRequest(ctx context.Context, op Operation, path string, query url.Values, header http.Header, payload interface{}) (*http.Response, error) {
+	invoke := func(ctx context.Context) (*http.Response, error) {
+		return a.doRetrying(ctx, op, path, query, header, payload)
+	}
+	for i := len(a.middlewares) - 1; i >= 0; i-- {
+		invoke = a.middlewares[i](op, invoke)
+	}
+	return invoke(ctx)
+}
+
+// doRetrying performs the operation, retrying it according to the configured RetryPolicy.
+// The request is built again on each attempt, so the payload is marshaled every time,
+// and it's sent to a different endpoint when possible.
+func (a *API) doRetrying(ctx context.Context, op Operation, path string, query url.Values, header http.Header, payload interface{}) (*http.Response, error) {
+	attempts := a.retryPolicy.attemptsFor(op)
+	var tried []*endpoint
+	prepare := func(ctx context.Context) (*endpoint, *http.Request, error) {
+		e := a.balancer.pick(tried)
+		tried = append(tried, e)
+		req, err := a.request(ctx, e.url, op.Method, path, query, header, payload)
+		if err != nil {
+			a.balancer.release(e)
+			return nil, nil, err
+		}
+		return e, req, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		e, req, err := prepare(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var resp *http.Response
+		if a.hedger.applies(op) {
+			resp, err = a.sendHedged(ctx, op, e, req, prepare, attempt)
+		} else {
+			resp, err = a.send(ctx, op, e, req, attempt)
+		}
+		if attempt >= attempts || !a.retryPolicy.shouldRetry(ctx, resp, err) {
+			if err != nil {
+				return nil, fmt.Errorf("can't perform http request: %w", redactURLError(err))
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			drainAndClose(resp.Body)
+		}
+		if err := a.retryPolicy.wait(ctx, attempt, resp); err != nil {
+			return nil, fmt.Errorf("can't perform http request: %w", err)
+		}
 	}
-	return resp, nil
 }
 
-func (a *API) request(ctx context.Context, method, path string, payload interface{}) (*http.Request, error) {
+// send performs a single attempt of the operation with the request prepared for the endpoint.
+func (a *API) send(ctx context.Context, op Operation, e *endpoint, req *http.Request, attempt int) (resp *http.Response, err error) {
+	// requests rejected before being sent say nothing about the endpoint
+	if a.limiter != nil {
+		if err := a.limiter.wait(ctx); err != nil {
+			a.balancer.release(e)
+			return nil, err
+		}
+	}
+	if a.breaker != nil {
+		if err := a.breaker.allow(op); err != nil {
+			a.balancer.release(e)
+			return nil, err
+		}
+		defer func() { a.breaker.record(ctx, op, resp, err) }()
+	}
+	start := time.Now()
+	resp, err = a.httpClient.Do(req)
+	a.balancer.done(ctx, e, resp, err)
+	if a.logger != nil {
+		a.logAttempt(ctx, op, req, attempt, time.Since(start), resp, err)
+	}
+	return resp, err
+}
+
+func (a *API) request(ctx context.Context, baseURL, method, path string, query url.Values, header http.Header, payload interface{}) (*http.Request, error) {
 	var body io.Reader
 	if payload != nil {
 		data, err := json.Marshal(payload)
@@ -259,12 +585,24 @@ func (a *API) request(ctx context.Context, method, path string, payload interfac
 		}
 		body = bytes.NewReader(data)
 	}
-	url := a.cfg.URL + a.basePath + path
-	req, err := http.NewRequest(method, url, body)
+	endpoint := baseURL + a.basePath + path
+	req, err := http.NewRequest(method, endpoint, body)
 	if err != nil {
 		return nil, fmt.Errorf("can't build HTTP request: %w", err)
 	}
-	return req.WithContext(ctx), nil
+	if len(query) > 0 {
+		req.URL.RawQuery = query.Encode()
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req = req.WithContext(ctx)
+	for _, edit := range a.requestEditors {
+		if err := edit(ctx, req); err != nil {
+			return nil, fmt.Errorf("can't edit HTTP request: %w", err)
+		}
+	}
+	return req, nil
 }
 
 func (a *API) unmarshalUserResponse(resp *http.Response) (*User, error) {
@@ -286,15 +624,22 @@ func (a *API) unmarshalErrorResponse(resp *http.Response) error {
 	}
 }
 
-// Error is returned by client methods when API returns a non-success status code which is documented.
-type Error struct {
-	StatusCode int
-	Response   *ErrorResponse
+// retryAfterError builds the RetryAfterError, the body is decoded if possible, since it can be provided by a proxy.
+func (a *API) retryAfterError(resp *http.Response) error {
+	retryAfter, _ := parseRetryAfter(resp.Header, time.Now())
+	err := RetryAfterError{StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	var respError ErrorResponse
+	if json.NewDecoder(resp.Body).Decode(&respError) == nil {
+		err.Response = &respError
+	}
+	return err
 }
 
-func (e Error) Error() string {
-	if e.Response != nil {
-		return fmt.Sprintf("userservice responded %d: %s", e.StatusCode, e.Response.Message)
+func (a *API) unexpectedStatusError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, unexpectedStatusBodyLimit))
+	return UnexpectedStatusError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
 	}
-	return fmt.Sprintf("userservice responded %d", e.StatusCode)
 }
@@ -94,7 +94,7 @@ func TestAPI_CreateUser(t *testing.T) {
 				responsePayload: nil,
 			},
 			expectedReturnValue: nil,
-			expectedError:       errors.New("received unexpected status code 502"),
+			expectedError:       restuser.UnexpectedStatusError{StatusCode: http.StatusBadGateway},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -104,12 +104,12 @@ func TestAPI_CreateUser(t *testing.T) {
 			api := restuser.New(restuser.Config{URL: srv.URL})
 			res, err := api.CreateUser(context.Background(), someUserToCreate)
 			assert.Equal(t, tc.expectedReturnValue, res)
-			assert.Equal(t, tc.expectedError, err)
+			assertAPIError(t, tc.expectedError, err)
 		})
 	}
 
 	t.Run("nil user", func(t *testing.T) {
-		api := restuser.New(restuser.Config{"http://google.com"})
+		api := restuser.New(restuser.Config{URL: "http://google.com"})
 		_, err := api.CreateUser(context.Background(), nil)
 		assert.Error(t, err)
 	})
@@ -219,7 +219,7 @@ func TestAPI_UpdateUser(t *testing.T) {
 				responsePayload: nil,
 			},
 			expectedReturnValue: nil,
-			expectedError:       errors.New("received unexpected status code 502"),
+			expectedError:       restuser.UnexpectedStatusError{StatusCode: http.StatusBadGateway},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -229,12 +229,12 @@ func TestAPI_UpdateUser(t *testing.T) {
 			api := restuser.New(restuser.Config{URL: srv.URL})
 			res, err := api.UpdateUser(context.Background(), someUserToUpdate)
 			assert.Equal(t, tc.expectedReturnValue, res)
-			assert.Equal(t, tc.expectedError, err)
+			assertAPIError(t, tc.expectedError, err)
 		})
 	}
 
 	t.Run("nil user", func(t *testing.T) {
-		api := restuser.New(restuser.Config{"http://google.com"})
+		api := restuser.New(restuser.Config{URL: "http://google.com"})
 		_, err := api.UpdateUser(context.Background(), nil)
 		assert.Error(t, err)
 	})
@@ -304,7 +304,7 @@ func TestAPI_GetUser(t *testing.T) {
 				responsePayload: nil,
 			},
 			expectedReturnValue: nil,
-			expectedError:       errors.New("received unexpected status code 502"),
+			expectedError:       restuser.UnexpectedStatusError{StatusCode: http.StatusBadGateway},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -314,7 +314,7 @@ func TestAPI_GetUser(t *testing.T) {
 			api := restuser.New(restuser.Config{URL: srv.URL})
 			res, err := api.GetUser(context.Background(), someUser.ID)
 			assert.Equal(t, tc.expectedReturnValue, res)
-			assert.Equal(t, tc.expectedError, err)
+			assertAPIError(t, tc.expectedError, err)
 		})
 	}
 }
@@ -365,7 +365,7 @@ func TestAPI_DeleteUser(t *testing.T) {
 				responseStatus:  http.StatusBadGateway,
 				responsePayload: nil,
 			},
-			expectedError: errors.New("received unexpected status code 502"),
+			expectedError: restuser.UnexpectedStatusError{StatusCode: http.StatusBadGateway},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -374,7 +374,7 @@ func TestAPI_DeleteUser(t *testing.T) {
 
 			api := restuser.New(restuser.Config{URL: srv.URL})
 			err := api.DeleteUser(context.Background(), someUserID)
-			assert.Equal(t, tc.expectedError, err)
+			assertAPIError(t, tc.expectedError, err)
 		})
 	}
 }
@@ -433,6 +433,64 @@ func TestAPI_ListUsers(t *testing.T) {
 			expectedReturnValue: []restuser.User{spanishUser},
 			expectedError:       nil,
 		},
+		{
+			name: "happy case searched",
+			srv: testServerExpectations{
+				method:          http.MethodGet,
+				url:             "/v1/users?email=pepe%2Bsupport%40faceit.com&first_name=Francisco&last_name=Johnson&name=pepe&name_prefix=pe&q=fran+j",
+				responseStatus:  http.StatusOK,
+				responsePayload: []restuser.User{spanishUser},
+			},
+			params: restuser.ListUsersParams{
+				Email:      "pepe+support@faceit.com",
+				Name:       "pepe",
+				NamePrefix: "pe",
+				FirstName:  "Francisco",
+				LastName:   "Johnson",
+				Query:      "fran j",
+			},
+			expectedReturnValue: []restuser.User{spanishUser},
+			expectedError:       nil,
+		},
+		{
+			name: "happy case multi-value and ranges",
+			srv: testServerExpectations{
+				method:          http.MethodGet,
+				url:             "/v1/users?country=es&country=fr&created_after=2006-01-01T00%3A00%3A00Z&created_before=2008-01-01T00%3A00%3A00Z&id=c3e11b46-109c-11eb-adc1-0242ac120002&id=c3e11b46-109c-11eb-adc1-0242ac120003&updated_after=2006-01-02T14%3A04%3A05.5Z",
+				responseStatus:  http.StatusOK,
+				responsePayload: []restuser.User{frenchUser, spanishUser},
+			},
+			params: restuser.ListUsersParams{
+				Countries:     []string{"es", "fr"},
+				IDs:           []string{frenchUser.ID, spanishUser.ID},
+				CreatedAfter:  time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC),
+				UpdatedAfter:  time.Date(2006, 1, 2, 15, 4, 5, 500000000, time.FixedZone("CET", 3600)),
+			},
+			expectedReturnValue: []restuser.User{frenchUser, spanishUser},
+			expectedError:       nil,
+		},
+		{
+			name: "happy case sorted with fields",
+			srv: testServerExpectations{
+				method:         http.MethodGet,
+				url:            "/v1/users?fields=id%2Cname%2Ccountry&sort=created_at%2C-name",
+				responseStatus: http.StatusOK,
+				responsePayload: []map[string]string{
+					{"id": frenchUser.ID, "name": frenchUser.Name, "country": frenchUser.Country},
+					{"id": spanishUser.ID, "name": spanishUser.Name, "country": spanishUser.Country},
+				},
+			},
+			params: restuser.ListUsersParams{
+				Sort:   []restuser.SortKey{{Field: "created_at"}, {Field: "name", Descending: true}},
+				Fields: []string{"id", "name", "country"},
+			},
+			expectedReturnValue: []restuser.User{
+				{ID: frenchUser.ID, Name: frenchUser.Name, Country: frenchUser.Country},
+				{ID: spanishUser.ID, Name: spanishUser.Name, Country: spanishUser.Country},
+			},
+			expectedError: nil,
+		},
 		{
 			name: "bad request",
 			srv: testServerExpectations{
@@ -464,7 +522,7 @@ func TestAPI_ListUsers(t *testing.T) {
 				responsePayload: nil,
 			},
 			expectedReturnValue: nil,
-			expectedError:       errors.New("received unexpected status code 502"),
+			expectedError:       restuser.UnexpectedStatusError{StatusCode: http.StatusBadGateway},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -474,11 +532,30 @@ func TestAPI_ListUsers(t *testing.T) {
 			api := restuser.New(restuser.Config{URL: srv.URL})
 			res, err := api.ListUsers(context.Background(), tc.params)
 			assert.Equal(t, tc.expectedReturnValue, res)
-			assert.Equal(t, tc.expectedError, err)
+			assertAPIError(t, tc.expectedError, err)
 		})
 	}
 }
 
+func TestAPI_ListUsers_InvalidParams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request %s", req.URL)
+	}))
+	defer srv.Close()
+
+	someTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	api := restuser.New(restuser.Config{URL: srv.URL})
+	for _, params := range []restuser.ListUsersParams{
+		{CreatedAfter: someTime, CreatedBefore: someTime},
+		{CreatedAfter: someTime, CreatedBefore: someTime.Add(-time.Second)},
+		{Sort: []restuser.SortKey{{Field: ""}}},
+		{Fields: []string{"id,name"}},
+	} {
+		_, err := api.ListUsers(context.Background(), params)
+		assert.Error(t, err)
+	}
+}
+
 func TestWithBasePath(t *testing.T) {
 	someUser := &restuser.User{
 		ID:        "c3e11b46-109c-11eb-adc1-0242ac120002",
@@ -688,6 +765,20 @@ func TestAPI_CallsWithContext(t *testing.T) {
 	}
 }
 
+// assertAPIError asserts that err is the expected one.
+// The headers of an UnexpectedStatusError are ignored, since they're set by the http server.
+func assertAPIError(t *testing.T, expected, err error) {
+	var unexpected restuser.UnexpectedStatusError
+	if errors.As(err, &unexpected) {
+		unexpected.Header = nil
+		if len(unexpected.Body) == 0 {
+			unexpected.Body = nil
+		}
+		err = unexpected
+	}
+	assert.Equal(t, expected, err)
+}
+
 type testServerExpectations struct {
 	method          string
 	url             string
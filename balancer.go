@@ -0,0 +1,194 @@
+package restuser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LoadBalancingStrategy decides which endpoint performs each request.
+type LoadBalancingStrategy int
+
+const (
+	// RoundRobin sends the requests to each endpoint in turn.
+	RoundRobin LoadBalancingStrategy = iota
+	// LeastOutstandingRequests sends the requests to the endpoint with fewer requests in flight.
+	// Requests are in flight until their response body is closed.
+	LeastOutstandingRequests
+)
+
+// LoadBalancingPolicy configures how the requests are balanced among the Config.URLs.
+// Zero values are replaced by the ones from DefaultLoadBalancingPolicy when provided to WithLoadBalancing.
+type LoadBalancingPolicy struct {
+	Strategy LoadBalancingStrategy
+	// EjectAfter is the amount of consecutive failures after which an endpoint stops receiving requests.
+	EjectAfter int
+	// EjectFor is the time an endpoint is ejected, after which it receives requests again to probe whether it recovered.
+	// If the probe fails, it's ejected again.
+	EjectFor time.Duration
+}
+
+// DefaultLoadBalancingPolicy returns the LoadBalancingPolicy used to complete the zero values of the provided one.
+func DefaultLoadBalancingPolicy() LoadBalancingPolicy {
+	return LoadBalancingPolicy{
+		Strategy:   RoundRobin,
+		EjectAfter: 5,
+		EjectFor:   30 * time.Second,
+	}
+}
+
+// WithLoadBalancing configures how the requests are balanced when several Config.URLs are provided.
+// By default they are balanced with the DefaultLoadBalancingPolicy.
+// Network errors and 5xx responses are considered failures of the endpoint, errors caused by the context being done are not.
+func WithLoadBalancing(policy LoadBalancingPolicy) Option {
+	defaults := DefaultLoadBalancingPolicy()
+	if policy.EjectAfter == 0 {
+		policy.EjectAfter = defaults.EjectAfter
+	}
+	if policy.EjectFor == 0 {
+		policy.EjectFor = defaults.EjectFor
+	}
+	return func(api *API) {
+		api.balancing = policy
+	}
+}
+
+// endpoint is one of the URLs the service is available at.
+type endpoint struct {
+	url string
+
+	// the following fields are guarded by the balancer's mutex
+	outstanding  int
+	failures     int
+	ejectedUntil time.Time
+}
+
+type balancer struct {
+	policy LoadBalancingPolicy
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+}
+
+func newBalancer(cfg Config, policy LoadBalancingPolicy) *balancer {
+	urls := cfg.URLs
+	if len(urls) == 0 {
+		urls = []string{cfg.URL}
+	}
+	b := &balancer{policy: policy}
+	for _, u := range urls {
+		b.endpoints = append(b.endpoints, &endpoint{url: u})
+	}
+	return b
+}
+
+// pick chooses the endpoint to perform a request, preferring the ones that were not tried yet,
+// and marks it as having one more outstanding request.
+// If all the endpoints are ejected, they're all considered, since failing fast is not the balancer's job.
+func (b *balancer) pick(tried []*endpoint) *endpoint {
+	if len(b.endpoints) == 1 {
+		return b.endpoints[0]
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	available := func(e *endpoint) bool { return !now.Before(e.ejectedUntil) }
+	untried := func(e *endpoint) bool {
+		if !available(e) {
+			return false
+		}
+		for _, t := range tried {
+			if t == e {
+				return false
+			}
+		}
+		return true
+	}
+	for _, eligible := range []func(*endpoint) bool{untried, available, func(*endpoint) bool { return true }} {
+		if e := b.choose(eligible); e != nil {
+			e.outstanding++
+			return e
+		}
+	}
+	panic("no endpoints") // unreachable, the last filter accepts all of them
+}
+
+// choose applies the strategy to the eligible endpoints, b.mu should be held.
+func (b *balancer) choose(eligible func(*endpoint) bool) *endpoint {
+	var chosen *endpoint
+	chosenIndex := 0
+	// iterate starting at next, so ties are resolved in turns
+	for i := 0; i < len(b.endpoints); i++ {
+		index := (b.next + i) % len(b.endpoints)
+		e := b.endpoints[index]
+		if !eligible(e) {
+			continue
+		}
+		if chosen == nil || (b.policy.Strategy == LeastOutstandingRequests && e.outstanding < chosen.outstanding) {
+			chosen, chosenIndex = e, index
+		}
+		if b.policy.Strategy == RoundRobin {
+			break
+		}
+	}
+	if chosen != nil {
+		b.next = chosenIndex + 1
+	}
+	return chosen
+}
+
+// release is called instead of done when the request picked for the endpoint was not performed.
+func (b *balancer) release(e *endpoint) {
+	if len(b.endpoints) == 1 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e.outstanding--
+}
+
+// done records the result of a request performed by an endpoint returned by pick.
+// The request is outstanding until the response body is closed, so the body is wrapped to release the endpoint then.
+func (b *balancer) done(ctx context.Context, e *endpoint, resp *http.Response, err error) {
+	if len(b.endpoints) == 1 {
+		return
+	}
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	// the request was interrupted by the caller, it says nothing about the endpoint
+	ignored := err != nil && ctx.Err() != nil
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		e.outstanding--
+	} else {
+		resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() { b.release(e) }}
+	}
+	switch {
+	case ignored:
+	case failed:
+		e.failures++
+		if e.failures >= b.policy.EjectAfter {
+			e.ejectedUntil = time.Now().Add(b.policy.EjectFor)
+		}
+	default:
+		e.failures = 0
+	}
+}
+
+// releaseOnClose releases the endpoint when the response body is closed, only the first time.
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
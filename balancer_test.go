@@ -0,0 +1,204 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLoadBalancing(t *testing.T) {
+	const id = "c3e11b46-109c-11eb-adc1-0242ac120002"
+	ctx := context.Background()
+
+	// countingServer responds with the status returned by status, counting the calls.
+	countingServer := func(status func() int, calls *int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt64(calls, 1)
+			rw.WriteHeader(status())
+		}))
+	}
+	ok := func() int { return http.StatusNoContent }
+	failing := func() int { return http.StatusBadGateway }
+
+	t.Run("round robin", func(t *testing.T) {
+		var calls [3]int64
+		var urls []string
+		for i := range calls {
+			srv := countingServer(ok, &calls[i])
+			defer srv.Close()
+			urls = append(urls, srv.URL)
+		}
+
+		api := restuser.New(restuser.Config{URLs: urls})
+		for i := 0; i < 6; i++ {
+			require.NoError(t, api.DeleteUser(ctx, id))
+		}
+		assert.Equal(t, [3]int64{2, 2, 2}, calls)
+	})
+
+	t.Run("retries prefer a different endpoint", func(t *testing.T) {
+		var failingCalls, okCalls int64
+		failingSrv := countingServer(func() int { return http.StatusServiceUnavailable }, &failingCalls)
+		defer failingSrv.Close()
+		okSrv := countingServer(ok, &okCalls)
+		defer okSrv.Close()
+
+		api := restuser.New(restuser.Config{URLs: []string{failingSrv.URL, okSrv.URL}},
+			restuser.WithRetryPolicy(restuser.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		)
+		for i := 0; i < 4; i++ {
+			require.NoError(t, api.DeleteUser(ctx, id))
+		}
+		assert.Equal(t, int64(4), okCalls)
+	})
+
+	t.Run("ejects failing endpoints and probes them later", func(t *testing.T) {
+		var failingCalls, okCalls int64
+		failingSrv := countingServer(failing, &failingCalls)
+		defer failingSrv.Close()
+		okSrv := countingServer(ok, &okCalls)
+		defer okSrv.Close()
+
+		api := restuser.New(restuser.Config{URLs: []string{failingSrv.URL, okSrv.URL}},
+			restuser.WithLoadBalancing(restuser.LoadBalancingPolicy{EjectAfter: 2, EjectFor: 20 * time.Millisecond}),
+		)
+		for i := 0; i < 8; i++ {
+			_ = api.DeleteUser(ctx, id)
+		}
+		assert.Equal(t, int64(2), atomic.LoadInt64(&failingCalls))
+		assert.Equal(t, int64(6), atomic.LoadInt64(&okCalls))
+
+		time.Sleep(30 * time.Millisecond)
+		for i := 0; i < 4; i++ {
+			_ = api.DeleteUser(ctx, id)
+		}
+		assert.Equal(t, int64(3), atomic.LoadInt64(&failingCalls), "should be probed once and ejected again")
+	})
+
+	t.Run("all endpoints ejected are still used", func(t *testing.T) {
+		var calls [2]int64
+		first := countingServer(failing, &calls[0])
+		defer first.Close()
+		second := countingServer(failing, &calls[1])
+		defer second.Close()
+
+		api := restuser.New(restuser.Config{URLs: []string{first.URL, second.URL}},
+			restuser.WithLoadBalancing(restuser.LoadBalancingPolicy{EjectAfter: 1}),
+		)
+		for i := 0; i < 4; i++ {
+			_ = api.DeleteUser(ctx, id)
+		}
+		assert.Equal(t, int64(4), calls[0]+calls[1])
+	})
+
+	t.Run("least outstanding requests", func(t *testing.T) {
+		received, release := make(chan struct{}), make(chan struct{})
+		var slowCalls, fastCalls int64
+		slowSrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt64(&slowCalls, 1)
+			received <- struct{}{}
+			<-release
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		defer slowSrv.Close()
+		fastSrv := countingServer(ok, &fastCalls)
+		defer fastSrv.Close()
+
+		api := restuser.New(restuser.Config{URLs: []string{slowSrv.URL, fastSrv.URL}},
+			restuser.WithLoadBalancing(restuser.LoadBalancingPolicy{Strategy: restuser.LeastOutstandingRequests}),
+		)
+		done := make(chan error)
+		go func() { done <- api.DeleteUser(ctx, id) }()
+		<-received
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, api.DeleteUser(ctx, id))
+		}
+		close(release)
+		require.NoError(t, <-done)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&slowCalls))
+		assert.Equal(t, int64(3), atomic.LoadInt64(&fastCalls))
+	})
+
+	t.Run("bodies being read are outstanding", func(t *testing.T) {
+		received, release := make(chan struct{}), make(chan struct{})
+		var slowDeletes, fastCalls int64
+		// slowSrv responds the headers of the listings right away, and their body once released
+		slowSrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				atomic.AddInt64(&slowDeletes, 1)
+				rw.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_, _ = rw.Write([]byte("["))
+			rw.(http.Flusher).Flush()
+			received <- struct{}{}
+			<-release
+			_, _ = rw.Write([]byte("]"))
+		}))
+		defer slowSrv.Close()
+		fastSrv := countingServer(ok, &fastCalls)
+		defer fastSrv.Close()
+
+		api := restuser.New(restuser.Config{URLs: []string{slowSrv.URL, fastSrv.URL}},
+			restuser.WithLoadBalancing(restuser.LoadBalancingPolicy{Strategy: restuser.LeastOutstandingRequests}),
+		)
+		done := make(chan error)
+		go func() {
+			done <- api.StreamUsers(ctx, restuser.ListUsersParams{}, func(restuser.User) error { return nil })
+		}()
+		<-received
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, api.DeleteUser(ctx, id))
+		}
+		close(release)
+		require.NoError(t, <-done)
+		assert.Equal(t, int64(0), atomic.LoadInt64(&slowDeletes))
+		assert.Equal(t, int64(3), atomic.LoadInt64(&fastCalls))
+	})
+}
+
+func TestWithLoadBalancing_CircuitOpenDoesNotEject(t *testing.T) {
+	const id = "c3e11b46-109c-11eb-adc1-0242ac120002"
+	ctx := context.Background()
+
+	var deletes [2]int64
+	var urls []string
+	for i := range deletes {
+		calls := &deletes[i]
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodDelete {
+				atomic.AddInt64(calls, 1)
+				rw.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// undocumented, so it opens the circuit without failing the endpoint
+			rw.WriteHeader(http.StatusTeapot)
+		}))
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+
+	api := restuser.New(restuser.Config{URLs: urls},
+		restuser.WithLoadBalancing(restuser.LoadBalancingPolicy{EjectAfter: 1, EjectFor: time.Hour}),
+		restuser.WithCircuitBreaker(restuser.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour}),
+	)
+	_, err := api.GetUser(ctx, id)
+	require.Error(t, err)
+	_, err = api.GetUser(ctx, id)
+	require.True(t, errors.Is(err, restuser.ErrCircuitOpen), "expected open circuit, got %v", err)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, api.DeleteUser(ctx, id))
+	}
+	assert.Equal(t, [2]int64{2, 2}, deletes, "no endpoint should be ejected by the open circuit")
+}
@@ -0,0 +1,108 @@
+package restuser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	batchGetPath    = usersPath + ":batchGet"
+	batchCreatePath = usersPath + ":batchCreate"
+	batchDeletePath = usersPath + ":batchDelete"
+)
+
+// BatchGetUsers retrieves several users by their IDs, returning the result of each one of them in the same order.
+// Users that don't exist have a result with the 404 status, see BatchResult.Err.
+// @Summary Retrieve several users by their IDs.
+// @Description Each result has the user or the error of the ID in the same position of the request.
+// @Description This operation does not return the PasswordHash and PasswordSalt fields for security reasons.
+// @ID batch-get-users
+// @Accept json
+// @Produce json
+// @Param request body BatchGetRequest true "IDs of the users, at most 100"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Router /users:batchGet [post]
+func (a *API) BatchGetUsers(ctx context.Context, ids []string) ([]BatchResult, error) {
+	return a.doBatch(ctx, opBatchGetUsers, batchGetPath, BatchGetRequest{IDs: ids}, len(ids))
+}
+
+// BatchCreateUsers creates several users, returning the result of each one of them in the same order.
+// @Summary Create several users.
+// @Description Each result has the created user or the error of the user in the same position of the request.
+// @Description In `all_or_nothing` mode, no user is created if any of them fails,
+// @Description and the results of the ones that didn't fail have the 424 status.
+// @ID batch-create-users
+// @Accept json
+// @Produce json
+// @Param request body BatchCreateRequest true "Users to create, at most 100"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Router /users:batchCreate [post]
+func (a *API) BatchCreateUsers(ctx context.Context, users []User, mode BatchMode) ([]BatchResult, error) {
+	return a.doBatch(ctx, opBatchCreateUsers, batchCreatePath, BatchCreateRequest{Users: users, Mode: mode}, len(users))
+}
+
+// BatchDeleteUsers deletes several users by their IDs, returning the result of each one of them in the same order.
+// @Summary Delete several users by their IDs.
+// @Description Each result has the error, if any, of the ID in the same position of the request.
+// @Description In `all_or_nothing` mode, no user is deleted if any of them fails,
+// @Description and the results of the ones that didn't fail have the 424 status.
+// @ID batch-delete-users
+// @Accept json
+// @Produce json
+// @Param request body BatchDeleteRequest true "IDs of the users, at most 100"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Router /users:batchDelete [post]
+func (a *API) BatchDeleteUsers(ctx context.Context, ids []string, mode BatchMode) ([]BatchResult, error) {
+	for _, id := range ids {
+		defer a.uncacheUser(id)
+	}
+	return a.doBatch(ctx, opBatchDeleteUsers, batchDeletePath, BatchDeleteRequest{IDs: ids, Mode: mode}, len(ids))
+}
+
+// doBatch performs the batch operation, checking that there's a result for each one of the items requested.
+func (a *API) doBatch(ctx context.Context, op Operation, path string, payload interface{}, items int) ([]BatchResult, error) {
+	resp, err := a.doRequest(ctx, op, path, nil, nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var batch BatchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+			return nil, fmt.Errorf("response was %d, however can't unmarshal batch JSON: %w", resp.StatusCode, err)
+		}
+		if len(batch.Results) != items {
+			return nil, fmt.Errorf("requested %d items, however %d results were responded", items, len(batch.Results))
+		}
+		return batch.Results, nil
+	case http.StatusBadRequest,
+		http.StatusInternalServerError:
+		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return nil, a.retryAfterError(resp)
+	default:
+		return nil, a.unexpectedStatusError(resp)
+	}
+}
@@ -0,0 +1,104 @@
+package restuser_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchOperations(t *testing.T) {
+	ctx := context.Background()
+	const missing = "c3e11b46-109c-11eb-adc1-0242ac120002"
+
+	t.Run("best effort", func(t *testing.T) {
+		srv := restusertest.NewServer()
+		defer srv.Close()
+		api := srv.API()
+
+		created, err := api.BatchCreateUsers(ctx, []restuser.User{
+			{Name: "first", Password: "password123"},
+			{Name: "invalid", Password: "short"},
+			{Name: "second", Password: "password123"},
+		}, restuser.BestEffort)
+		require.NoError(t, err)
+		require.Len(t, created, 3)
+		assert.Equal(t, http.StatusCreated, created[0].Status)
+		assert.Equal(t, "first", created[0].User.Name)
+		assert.True(t, restuser.IsBadRequest(created[1].Err()))
+		assert.Nil(t, created[1].User)
+		assert.Equal(t, "second", created[2].User.Name)
+
+		got, err := api.BatchGetUsers(ctx, []string{created[2].User.ID, missing, created[0].User.ID})
+		require.NoError(t, err)
+		require.Len(t, got, 3)
+		assert.NoError(t, got[0].Err())
+		assert.Equal(t, created[2].User.ID, got[0].User.ID)
+		assert.Empty(t, got[0].User.PasswordHash)
+		assert.True(t, restuser.IsNotFound(got[1].Err()))
+		assert.Equal(t, created[0].User.ID, got[2].User.ID)
+
+		deleted, err := api.BatchDeleteUsers(ctx, []string{created[0].User.ID, missing}, restuser.BestEffort)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, deleted[0].Status)
+		assert.NoError(t, deleted[0].Err())
+		assert.True(t, restuser.IsNotFound(deleted[1].Err()))
+
+		_, err = api.GetUser(ctx, created[0].User.ID)
+		assert.True(t, restuser.IsNotFound(err))
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		srv := restusertest.NewServer()
+		defer srv.Close()
+		api := srv.API()
+
+		created, err := api.BatchCreateUsers(ctx, []restuser.User{
+			{Name: "first", Password: "password123"},
+			{Name: "invalid", Password: "short"},
+		}, restuser.AllOrNothing)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFailedDependency, created[0].Status)
+		assert.Error(t, created[0].Err())
+		assert.True(t, restuser.IsBadRequest(created[1].Err()))
+
+		users, err := api.ListUsers(ctx, restuser.ListUsersParams{})
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		user, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+		deleted, err := api.BatchDeleteUsers(ctx, []string{user.ID, missing}, restuser.AllOrNothing)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFailedDependency, deleted[0].Status)
+		assert.True(t, restuser.IsNotFound(deleted[1].Err()))
+
+		_, err = api.GetUser(ctx, user.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		srv := restusertest.NewServer()
+		defer srv.Close()
+
+		_, err := srv.API().BatchDeleteUsers(ctx, []string{missing}, restuser.BatchMode("sometimes"))
+		assert.True(t, restuser.IsBadRequest(err))
+	})
+
+	t.Run("results mismatch", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, "/v1/users:batchGet", req.URL.Path)
+			_, _ = rw.Write([]byte(`{"results":[]}`))
+		}))
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL})
+		_, err := api.BatchGetUsers(ctx, []string{missing})
+		assert.EqualError(t, err, "requested 1 items, however 0 results were responded")
+	})
+}
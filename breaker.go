@@ -0,0 +1,181 @@
+package restuser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped, when a request is not performed because the circuit breaker of its operation is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker of an operation.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all the requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all the requests without performing them.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited amount of requests through to probe whether the service has recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breaker.
+// Zero values are replaced by the ones from DefaultCircuitBreakerConfig when provided to WithCircuitBreaker.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the amount of consecutive failures that open the circuit.
+	FailureThreshold int
+	// OpenTimeout is the time the circuit stays open before letting probe requests through.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the amount of probe requests let through while half-open,
+	// all of them should succeed to close the circuit again.
+	HalfOpenProbes int
+	// OnStateChange is called, if provided, each time the circuit of an operation changes its state.
+	// It's called synchronously by the goroutine performing the request, so it shouldn't block.
+	OnStateChange func(operation string, from, to CircuitState)
+}
+
+// DefaultCircuitBreakerConfig returns the CircuitBreakerConfig used to complete the zero values of the provided one.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// WithCircuitBreaker configures the API to stop performing the requests of an operation after consecutive failures,
+// failing fast with ErrCircuitOpen until the OpenTimeout passes and probe requests succeed.
+// Network errors, 5xx responses and status codes not documented for the operation are considered failures,
+// errors caused by the context being done are not.
+// Each attempt performed by the RetryPolicy is tracked, and circuit breaker errors are not retried.
+func WithCircuitBreaker(cfg CircuitBreakerConfig) Option {
+	defaults := DefaultCircuitBreakerConfig()
+	if cfg.FailureThreshold == 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.OpenTimeout == 0 {
+		cfg.OpenTimeout = defaults.OpenTimeout
+	}
+	if cfg.HalfOpenProbes == 0 {
+		cfg.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+	return func(api *API) {
+		api.breaker = &circuitBreaker{cfg: cfg, circuits: map[string]*circuit{}}
+	}
+}
+
+// circuitBreaker keeps a circuit for each operation.
+type circuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// probes is the amount of requests let through while half-open, and succeeded those of them that succeeded.
+	probes    int
+	succeeded int
+}
+
+// allow returns an error if the request can't be performed.
+// If it returns nil, record should be called with the result of the request.
+func (b *circuitBreaker) allow(op Operation) error {
+	b.mu.Lock()
+	c := b.circuit(op)
+	from := c.state
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= b.cfg.OpenTimeout {
+		c.state, c.probes, c.succeeded = CircuitHalfOpen, 0, 0
+	}
+	allowed := c.state == CircuitClosed || (c.state == CircuitHalfOpen && c.probes < b.cfg.HalfOpenProbes)
+	if allowed && c.state == CircuitHalfOpen {
+		c.probes++
+	}
+	to := c.state
+	b.mu.Unlock()
+
+	b.notify(op, from, to)
+	if !allowed {
+		return fmt.Errorf("%w for operation %s", ErrCircuitOpen, op.ID)
+	}
+	return nil
+}
+
+// record updates the circuit of the operation with the result of a request allowed by allow.
+func (b *circuitBreaker) record(ctx context.Context, op Operation, resp *http.Response, err error) {
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError || !op.documents(resp.StatusCode)
+	// the request was interrupted by the caller, it says nothing about the service
+	ignored := err != nil && ctx.Err() != nil
+
+	b.mu.Lock()
+	c := b.circuit(op)
+	from := c.state
+	switch c.state {
+	case CircuitClosed:
+		switch {
+		case ignored:
+		case failed:
+			c.failures++
+			if c.failures >= b.cfg.FailureThreshold {
+				c.state, c.openedAt = CircuitOpen, time.Now()
+			}
+		default:
+			c.failures = 0
+		}
+	case CircuitHalfOpen:
+		switch {
+		case ignored:
+			c.probes--
+		case failed:
+			c.state, c.openedAt = CircuitOpen, time.Now()
+		default:
+			c.succeeded++
+			if c.succeeded >= b.cfg.HalfOpenProbes {
+				c.state, c.failures = CircuitClosed, 0
+			}
+		}
+	case CircuitOpen:
+		// a request performed before opening the circuit, it doesn't change anything
+	}
+	to := c.state
+	b.mu.Unlock()
+
+	b.notify(op, from, to)
+}
+
+// circuit returns the circuit of the operation, b.mu should be held.
+func (b *circuitBreaker) circuit(op Operation) *circuit {
+	c, ok := b.circuits[op.ID]
+	if !ok {
+		c = &circuit{}
+		b.circuits[op.ID] = c
+	}
+	return c
+}
+
+func (b *circuitBreaker) notify(op Operation, from, to CircuitState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(op.ID, from, to)
+	}
+}
@@ -0,0 +1,109 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCircuitBreaker(t *testing.T) {
+	const id = "c3e11b46-109c-11eb-adc1-0242ac120002"
+	ctx := context.Background()
+
+	var status, calls int64
+	atomic.StoreInt64(&status, http.StatusBadGateway)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		rw.WriteHeader(int(atomic.LoadInt64(&status)))
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithCircuitBreaker(restuser.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      10 * time.Millisecond,
+		OnStateChange: func(operation string, from, to restuser.CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, operation+": "+from.String()+" -> "+to.String())
+		},
+	}))
+
+	for i := 0; i < 2; i++ {
+		err := api.DeleteUser(ctx, id)
+		var unexpected restuser.UnexpectedStatusError
+		require.True(t, errors.As(err, &unexpected))
+	}
+
+	err := api.DeleteUser(ctx, id)
+	assert.True(t, errors.Is(err, restuser.ErrCircuitOpen))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&calls), "request should not be performed while open")
+
+	// other operations have their own circuit
+	atomic.StoreInt64(&status, http.StatusNoContent)
+	_, err = api.GetUser(ctx, id)
+	assert.False(t, errors.Is(err, restuser.ErrCircuitOpen))
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, api.DeleteUser(ctx, id))
+	require.NoError(t, api.DeleteUser(ctx, id))
+
+	assert.Equal(t, []string{
+		"delete-user: closed -> open",
+		"delete-user: open -> half-open",
+		"delete-user: half-open -> closed",
+	}, changes)
+}
+
+func TestWithCircuitBreaker_FailedProbeOpensAgain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+		_, _ = rw.Write([]byte(`{"message":"internal error"}`))
+	}))
+	defer srv.Close()
+
+	var states []restuser.CircuitState
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithCircuitBreaker(restuser.CircuitBreakerConfig{
+		FailureThreshold: 1,
+		OpenTimeout:      10 * time.Millisecond,
+		OnStateChange: func(_ string, _, to restuser.CircuitState) {
+			states = append(states, to)
+		},
+	}))
+
+	ctx := context.Background()
+	_, err := api.ListUsers(ctx, restuser.ListUsersParams{})
+	assert.True(t, restuser.IsInternal(err))
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = api.ListUsers(ctx, restuser.ListUsersParams{})
+	assert.True(t, restuser.IsInternal(err))
+	_, err = api.ListUsers(ctx, restuser.ListUsersParams{})
+	assert.True(t, errors.Is(err, restuser.ErrCircuitOpen))
+
+	assert.Equal(t, []restuser.CircuitState{restuser.CircuitOpen, restuser.CircuitHalfOpen, restuser.CircuitOpen}, states)
+}
+
+func TestWithCircuitBreaker_DocumentedErrorsAreNotFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		_, _ = rw.Write([]byte(`{"message":"user not found"}`))
+	}))
+	defer srv.Close()
+
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithCircuitBreaker(restuser.CircuitBreakerConfig{FailureThreshold: 1}))
+	for i := 0; i < 3; i++ {
+		_, err := api.GetUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+		assert.True(t, restuser.IsNotFound(err))
+	}
+}
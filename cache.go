@@ -0,0 +1,206 @@
+package restuser
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache stores the users retrieved by GetUser, along with their ETag, to revalidate them with the service.
+// Implementations should be safe for concurrent use.
+type Cache interface {
+	// Get returns the entry of the user with the given ID, if any.
+	Get(id string) (CacheEntry, bool)
+	// Set stores the entry of the user with the given ID.
+	Set(id string, entry CacheEntry)
+	// Delete removes the entry of the user with the given ID, if any.
+	Delete(id string)
+}
+
+// CacheEntry is a user stored in the Cache.
+type CacheEntry struct {
+	User User
+	// ETag is the version of the user, provided as If-None-Match to revalidate it.
+	ETag string
+}
+
+func (e CacheEntry) user() *User {
+	user := e.User
+	return &user
+}
+
+// WithCache configures the API to cache the users retrieved by GetUser.
+// Cached users are revalidated with the service on each GetUser, which responds 304 without the body if they're not modified.
+// Users updated or deleted through this API are removed from the cache, and not cached by the GetUser calls
+// retrieving them meanwhile. Changes performed by other clients are seen when revalidating.
+func WithCache(cache Cache) Option {
+	return func(api *API) {
+		api.cache = cache
+	}
+}
+
+// WithStaleIfError configures the API to return the user from the Cache configured by WithCache
+// when it can't be revalidated because of a network error or a 5xx response.
+func WithStaleIfError() Option {
+	return func(api *API) {
+		api.staleIfError = true
+	}
+}
+
+func (a *API) cachedUser(id string) (CacheEntry, bool) {
+	if a.cache == nil {
+		return CacheEntry{}, false
+	}
+	return a.cache.Get(id)
+}
+
+// cacheUser stores the user, unless the service didn't provide the ETag, since it couldn't be revalidated.
+func (a *API) cacheUser(user *User, etag string) {
+	if a.cache == nil || etag == "" {
+		return
+	}
+	a.cache.Set(user.ID, CacheEntry{User: *user, ETag: etag})
+}
+
+func (a *API) uncacheUser(id string) {
+	if a.cache != nil {
+		a.cache.Delete(id)
+		a.fetches.evict(id)
+	}
+}
+
+// fetchTracker tracks the users being retrieved, so they're not cached if they're evicted meanwhile.
+// The zero value is ready to use.
+type fetchTracker struct {
+	mu      sync.Mutex
+	fetches map[string]*fetch
+}
+
+// fetch counts the retrievals in flight of a user, and the evictions of that user since they started.
+type fetch struct {
+	inFlight   int
+	generation uint64
+}
+
+// start registers a retrieval in flight, returning the generation to be provided to finish.
+func (t *fetchTracker) start(id string) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fetches == nil {
+		t.fetches = map[string]*fetch{}
+	}
+	f, ok := t.fetches[id]
+	if !ok {
+		f = &fetch{}
+		t.fetches[id] = f
+	}
+	f.inFlight++
+	return f.generation
+}
+
+// finish unregisters a retrieval, reporting whether the user wasn't evicted since it started.
+func (t *fetchTracker) finish(id string, generation uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f := t.fetches[id]
+	f.inFlight--
+	if f.inFlight == 0 {
+		delete(t.fetches, id)
+	}
+	return f.generation == generation
+}
+
+// evict makes the retrievals in flight of the user not to be cached.
+func (t *fetchTracker) evict(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.fetches[id]; ok {
+		f.generation++
+	}
+}
+
+// LRUCache is a Cache keeping a limited amount of entries for a limited time,
+// evicting the least recently used ones when it's full.
+type LRUCache struct {
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order has the *lruEntry values, most recently used first
+	order *list.List
+}
+
+type lruEntry struct {
+	id      string
+	entry   CacheEntry
+	expires time.Time
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+// NewLRUCache creates an LRUCache keeping at most maxEntries entries, or unlimited if maxEntries is not positive,
+// for at most ttl, or forever if ttl is not positive.
+func NewLRUCache(maxEntries int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(id string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[id]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	e := elem.Value.(*lruEntry)
+	if c.ttl > 0 && time.Now().After(e.expires) {
+		c.remove(elem)
+		return CacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return e.entry, true
+}
+
+// Set implements Cache.
+func (c *LRUCache) Set(id string, entry CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &lruEntry{id: id, entry: entry, expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[id]; ok {
+		elem.Value = e
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[id] = c.order.PushFront(e)
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete implements Cache.
+func (c *LRUCache) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the amount of entries in the cache, including the expired ones not evicted yet.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove removes the element, c.mu should be held.
+func (c *LRUCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).id)
+}
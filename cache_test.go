@@ -0,0 +1,198 @@
+package restuser_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCache(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var statuses []int
+	recorder := restuser.WithMiddleware(func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+		return func(ctx context.Context) (*http.Response, error) {
+			resp, err := next(ctx)
+			if err == nil && op.ID == "get-user" {
+				statuses = append(statuses, resp.StatusCode)
+			}
+			return resp, err
+		}
+	})
+	cache := restuser.NewLRUCache(10, time.Minute)
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithCache(cache), recorder)
+
+	created, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+
+	t.Run("revalidates cached users", func(t *testing.T) {
+		statuses = nil
+		for i := 0; i < 2; i++ {
+			got, err := api.GetUser(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, created, got)
+		}
+		assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, statuses)
+	})
+
+	t.Run("sees changes performed by other clients", func(t *testing.T) {
+		toUpdate := *created
+		toUpdate.Name = "pepito"
+		updated, err := srv.API().UpdateUser(ctx, &toUpdate)
+		require.NoError(t, err)
+
+		statuses = nil
+		got, err := api.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updated, got)
+		assert.Equal(t, []int{http.StatusOK}, statuses)
+	})
+
+	t.Run("invalidates updated and deleted users", func(t *testing.T) {
+		got, err := api.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		_, ok := cache.Get(created.ID)
+		require.True(t, ok)
+
+		got.Name = "pepe"
+		_, err = api.UpdateUser(ctx, got)
+		require.NoError(t, err)
+		_, ok = cache.Get(created.ID)
+		assert.False(t, ok)
+
+		_, err = api.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		require.NoError(t, api.DeleteUser(ctx, created.ID))
+		_, ok = cache.Get(created.ID)
+		assert.False(t, ok)
+	})
+}
+
+func TestWithCache_EvictedWhileRetrieved(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	// race is called once, after a user is retrieved and before the response is processed
+	var race func()
+	racing := restuser.WithMiddleware(func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+		return func(ctx context.Context) (*http.Response, error) {
+			resp, err := next(ctx)
+			if op.ID == "get-user" && race != nil {
+				r := race
+				race = nil
+				r()
+			}
+			return resp, err
+		}
+	})
+	cache := restuser.NewLRUCache(10, time.Minute)
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithCache(cache), racing)
+
+	created, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+
+	race = func() { require.NoError(t, api.DeleteUser(ctx, created.ID)) }
+	got, err := api.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	_, ok := cache.Get(created.ID)
+	assert.False(t, ok)
+
+	_, err = api.GetUser(ctx, created.ID)
+	assert.True(t, restuser.IsNotFound(err))
+}
+
+func TestWithStaleIfError(t *testing.T) {
+	someUser := &restuser.User{ID: "c3e11b46-109c-11eb-adc1-0242ac120002", UpdatedAt: "2006-01-02T15:04:05Z", Name: "pepe"}
+	var failing int64
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt64(&failing) == 1 {
+			rw.WriteHeader(http.StatusInternalServerError)
+			_, _ = rw.Write([]byte(`{"message":"internal error"}`))
+			return
+		}
+		rw.Header().Set("ETag", `"`+someUser.UpdatedAt+`"`)
+		require.NoError(t, json.NewEncoder(rw).Encode(someUser))
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name   string
+		stale  bool
+		failed bool
+	}{
+		{name: "serves stale entries when configured", stale: true},
+		{name: "fails otherwise", failed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt64(&failing, 0)
+			options := []restuser.Option{restuser.WithCache(restuser.NewLRUCache(10, 0))}
+			if tc.stale {
+				options = append(options, restuser.WithStaleIfError())
+			}
+			api := restuser.New(restuser.Config{URL: srv.URL}, options...)
+			_, err := api.GetUser(context.Background(), someUser.ID)
+			require.NoError(t, err)
+
+			atomic.StoreInt64(&failing, 1)
+			got, err := api.GetUser(context.Background(), someUser.ID)
+			if tc.failed {
+				assert.True(t, restuser.IsInternal(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, someUser, got)
+		})
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	entry := func(name string) restuser.CacheEntry {
+		return restuser.CacheEntry{User: restuser.User{Name: name}, ETag: `"` + name + `"`}
+	}
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		cache := restuser.NewLRUCache(2, 0)
+		cache.Set("first", entry("first"))
+		cache.Set("second", entry("second"))
+		_, _ = cache.Get("first")
+		cache.Set("third", entry("third"))
+
+		assert.Equal(t, 2, cache.Len())
+		_, ok := cache.Get("second")
+		assert.False(t, ok)
+		got, ok := cache.Get("first")
+		assert.True(t, ok)
+		assert.Equal(t, entry("first"), got)
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		cache := restuser.NewLRUCache(0, time.Nanosecond)
+		cache.Set("first", entry("first"))
+		time.Sleep(time.Millisecond)
+		_, ok := cache.Get("first")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		cache := restuser.NewLRUCache(0, 0)
+		cache.Set("first", entry("first"))
+		cache.Delete("first")
+		cache.Delete("second")
+		_, ok := cache.Get("first")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/importexport"
+)
+
+func exportCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	flags := newFlagSet("export", "", out)
+	format := flags.String("format", string(importexport.JSONL), "output file format: jsonl or csv")
+	columns := flags.String("columns", "", "comma separated columns to export, as field or name=field, defaults to all fields")
+	file := flags.String("file", "-", "output file, - for standard output")
+	var opts importexport.ExportOptions
+	flags.StringVar(&opts.Params.Country, "country", "", "filter by country code")
+	flags.IntVar(&opts.Params.Limit, "page-size", 0, "amount of users retrieved on each request")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *columns != "" {
+		var err error
+		if opts.Columns, err = importexport.ParseColumns(*columns); err != nil {
+			fmt.Fprintln(out.stderr, err)
+			return errUsage
+		}
+	}
+
+	w := out.stdout
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("can't create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count, err := importexport.Export(ctx, api, w, importexport.Format(*format), opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out.stderr, "exported %d users\n", count)
+	return nil
+}
+
+// errImportFailedRows is returned by the import command when some rows couldn't be imported.
+var errImportFailedRows = errors.New("some rows couldn't be imported")
+
+func importCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	flags := newFlagSet("import", "", out)
+	format := flags.String("format", string(importexport.JSONL), "input file format: jsonl or csv")
+	columns := flags.String("columns", "", "comma separated columns of the input, as field or name=field, defaults to field names")
+	file := flags.String("file", "-", "input file, - for standard input")
+	checkpoint := flags.String("checkpoint", "", "file to store the progress, the import is resumed from it if it exists")
+	var opts importexport.ImportOptions
+	flags.IntVar(&opts.Concurrency, "concurrency", 1, "maximum amount of users created at the same time")
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "only validate the input, without creating the users")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *columns != "" {
+		var err error
+		if opts.Columns, err = importexport.ParseColumns(*columns); err != nil {
+			fmt.Fprintln(out.stderr, err)
+			return errUsage
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("can't open input file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if *checkpoint != "" && !opts.DryRun {
+		var err error
+		if opts.Skip, err = readCheckpoint(*checkpoint); err != nil {
+			return err
+		}
+		opts.Checkpoint = func(rows int) error { return writeCheckpoint(*checkpoint, rows) }
+	}
+
+	report, err := importexport.Import(ctx, api, r, importexport.Format(*format), opts)
+	if report != nil {
+		for _, rowErr := range report.Errors {
+			fmt.Fprintln(out.stderr, rowErr)
+		}
+		fmt.Fprintf(out.stderr, "processed %d rows, skipped %d, created %d users, %d errors\n",
+			report.Processed, opts.Skip, report.Created, len(report.Errors))
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return errImportFailedRows
+	}
+	return nil
+}
+
+func readCheckpoint(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("can't read checkpoint: %w", err)
+	}
+	rows, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	return rows, nil
+}
+
+// writeCheckpoint writes the checkpoint to a temporary file first, so it's never left half-written.
+func writeCheckpoint(path string, rows int) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(rows)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
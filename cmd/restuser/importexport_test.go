@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := restusertest.NewServer()
+	defer source.Close()
+	target := restusertest.NewServer()
+	defer target.Close()
+
+	for _, name := range []string{"pepe", "paco"} {
+		_, err := source.API().CreateUser(ctx, &restuser.User{Name: name, Password: "password123", Country: "es"})
+		require.NoError(t, err)
+	}
+
+	dir := t.TempDir()
+	exported := filepath.Join(dir, "users.csv")
+	var stdout, stderr bytes.Buffer
+	code := run(ctx, []string{"-url", source.URL, "export", "-format", "csv", "-columns", "nickname=name,country", "-file", exported}, noenv, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Contains(t, stderr.String(), "exported 2 users")
+
+	// passwords can't be exported, so add them
+	data, err := ioutil.ReadFile(exported)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] += ",password"
+		} else {
+			lines[i] += ",password123"
+		}
+	}
+	toImport := filepath.Join(dir, "import.csv")
+	require.NoError(t, ioutil.WriteFile(toImport, []byte(strings.Join(lines, "\n")), 0o644))
+
+	importArgs := []string{"-url", target.URL, "import", "-format", "csv", "-columns", "nickname=name,country,password", "-file", toImport}
+
+	t.Run("dry run", func(t *testing.T) {
+		code := run(ctx, append(importArgs, "-dry-run"), noenv, &stdout, &stderr)
+		require.Equal(t, exitOK, code, stderr.String())
+
+		users, err := target.API().ListUsers(ctx, restuser.ListUsersParams{})
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+
+	t.Run("import with checkpoint", func(t *testing.T) {
+		checkpoint := filepath.Join(dir, "checkpoint")
+		require.NoError(t, ioutil.WriteFile(checkpoint, []byte("1\n"), 0o644))
+
+		stderr.Reset()
+		code := run(ctx, append(importArgs, "-checkpoint", checkpoint), noenv, &stdout, &stderr)
+		require.Equal(t, exitOK, code, stderr.String())
+		assert.Contains(t, stderr.String(), "processed 1 rows, skipped 1, created 1 users, 0 errors")
+
+		users, err := target.API().ListUsers(ctx, restuser.ListUsersParams{})
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		data, err := ioutil.ReadFile(checkpoint)
+		require.NoError(t, err)
+		assert.Equal(t, "2\n", string(data))
+	})
+
+	t.Run("failed rows", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.jsonl")
+		require.NoError(t, ioutil.WriteFile(invalid, []byte(`{"name":"pepe","password":"short"}`), 0o644))
+
+		stderr.Reset()
+		code := run(ctx, []string{"-url", target.URL, "import", "-file", invalid}, noenv, &stdout, &stderr)
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr.String(), "row 1: password should be at least 8 characters long")
+	})
+}
+
+func noenv(string) string { return "" }
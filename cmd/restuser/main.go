@@ -0,0 +1,285 @@
+// Command restuser is a command-line client for the user service.
+//
+// Usage:
+//
+//	restuser [-url URL] [-o json|table|yaml] <command> [arguments]
+//
+// The commands are:
+//
+//	create -name NAME -password PASSWORD [-first-name ...] [-last-name ...] [-email ...] [-country ...]
+//	get ID
+//	update [-updated-at UPDATED_AT] [-name ...] [-password ...] [-first-name ...] [-last-name ...] [-email ...] [-country ...] ID
+//	delete ID
+//	list [-country COUNTRY] [-email EMAIL] [-name NAME] [-query QUERY] [-page-size N]
+//	export [-format jsonl|csv] [-columns COLUMNS] [-file FILE] [-country COUNTRY] [-page-size N]
+//	import [-format jsonl|csv] [-columns COLUMNS] [-file FILE] [-checkpoint FILE] [-concurrency N] [-dry-run]
+//
+// The URL can also be provided through the RESTUSER_URL environment variable.
+// When the update command is not provided the UpdatedAt value, the latest one is used, retrying on conflicts.
+// The columns of export and import are a comma separated list of field names, or column names and field names
+// separated by "=", like "id,nickname=name,country". The import command exits with 1 if any row fails.
+//
+// The exit code is 0 on success, 1 on unexpected errors, 2 on invalid usage,
+// 3 if the service responded bad request, 4 if it responded not found and 5 if it responded conflict.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+const urlEnvVar = "RESTUSER_URL"
+
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitBadRequest
+	exitNotFound
+	exitConflict
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+// errUsage is returned by commands when they're not invoked properly.
+var errUsage = errors.New("invalid usage")
+
+type command func(ctx context.Context, api *restuser.API, args []string, out *printer) error
+
+var commands = map[string]command{
+	"create": createCommand,
+	"get":    getCommand,
+	"update": updateCommand,
+	"delete": deleteCommand,
+	"list":   listCommand,
+	"export": exportCommand,
+	"import": importCommand,
+}
+
+func run(ctx context.Context, args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("restuser", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: restuser [-url URL] [-o json|table|yaml] create|get|update|delete|list|export|import [arguments]")
+		flags.PrintDefaults()
+	}
+	baseURL := flags.String("url", getenv(urlEnvVar), "base URL of the user service, defaults to $"+urlEnvVar)
+	format := flags.String("o", formatJSON, "output format: json, table or yaml")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return exitUsage
+	}
+	if *baseURL == "" {
+		fmt.Fprintf(stderr, "the user service URL should be provided with -url or $%s\n", urlEnvVar)
+		return exitUsage
+	}
+	out, err := newPrinter(stdout, stderr, *format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	api := restuser.New(restuser.Config{URL: *baseURL})
+	if err := cmd(ctx, api, flags.Args()[1:], out); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(stderr, err)
+		}
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case restuser.IsBadRequest(err):
+		return exitBadRequest
+	case restuser.IsNotFound(err):
+		return exitNotFound
+	case restuser.IsConflict(err), errors.Is(err, restuser.ErrModifyAttemptsExhausted):
+		return exitConflict
+	default:
+		return exitError
+	}
+}
+
+// userFlags registers the flags of the user fields that can be set on create and update.
+type userFlags struct {
+	set map[string]bool
+
+	firstName, lastName, name, email, password, country string
+}
+
+func newUserFlags(flags *flag.FlagSet) *userFlags {
+	uf := &userFlags{}
+	flags.StringVar(&uf.firstName, "first-name", "", "first name of the user")
+	flags.StringVar(&uf.lastName, "last-name", "", "last name of the user")
+	flags.StringVar(&uf.name, "name", "", "nickname of the user")
+	flags.StringVar(&uf.email, "email", "", "email of the user")
+	flags.StringVar(&uf.password, "password", "", "password of the user, at least 8 characters long")
+	flags.StringVar(&uf.country, "country", "", "country code of the user")
+	return uf
+}
+
+// apply sets the user fields for the flags that were provided.
+func (uf *userFlags) apply(flags *flag.FlagSet, user *restuser.User) {
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "first-name":
+			user.FirstName = uf.firstName
+		case "last-name":
+			user.LastName = uf.lastName
+		case "name":
+			user.Name = uf.name
+		case "email":
+			user.Email = uf.email
+		case "password":
+			user.Password = uf.password
+		case "country":
+			user.Country = uf.country
+		}
+	})
+}
+
+func createCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	flags := newFlagSet("create", "", out)
+	uf := newUserFlags(flags)
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	var user restuser.User
+	uf.apply(flags, &user)
+	created, err := api.CreateUser(ctx, &user)
+	if err != nil {
+		return err
+	}
+	return out.user(created)
+}
+
+func getCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	id, err := singleIDArg("get", args, out)
+	if err != nil {
+		return err
+	}
+	user, err := api.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.user(user)
+}
+
+func updateCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	flags := newFlagSet("update", "ID", out)
+	uf := newUserFlags(flags)
+	updatedAt := flags.String("updated-at", "", "UpdatedAt value of the user being updated, the update conflicts if it doesn't match")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	id := flags.Arg(0)
+
+	if *updatedAt == "" {
+		updated, err := api.ModifyUser(ctx, id, func(user *restuser.User) error {
+			uf.apply(flags, user)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return out.user(updated)
+	}
+
+	user, err := api.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	uf.apply(flags, user)
+	user.UpdatedAt = *updatedAt
+	user.PasswordHash = ""
+	user.PasswordSalt = ""
+	updated, err := api.UpdateUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	return out.user(updated)
+}
+
+func deleteCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	id, err := singleIDArg("delete", args, out)
+	if err != nil {
+		return err
+	}
+	return api.DeleteUser(ctx, id)
+}
+
+func listCommand(ctx context.Context, api *restuser.API, args []string, out *printer) error {
+	flags := newFlagSet("list", "", out)
+	var params restuser.ListUsersParams
+	flags.StringVar(&params.Country, "country", "", "filter by country code")
+	flags.StringVar(&params.Email, "email", "", "filter by email")
+	flags.StringVar(&params.Name, "name", "", "filter by nickname")
+	flags.StringVar(&params.Query, "query", "", "free-text search on the nickname, first name, last name and email")
+	flags.IntVar(&params.Limit, "page-size", 0, "amount of users retrieved on each request, all of them are listed anyway")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	users := []restuser.User{}
+	it := api.IterateUsers(params)
+	for it.Next(ctx) {
+		users = append(users, it.User())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return out.users(users)
+}
+
+func singleIDArg(name string, args []string, out *printer) (string, error) {
+	flags := newFlagSet(name, "ID", out)
+	if err := parse(flags, args, 1); err != nil {
+		return "", err
+	}
+	return flags.Arg(0), nil
+}
+
+func newFlagSet(name, arguments string, out *printer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(out.stderr, "usage: restuser %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses the flags, expecting the given amount of positional arguments.
+func parse(flags *flag.FlagSet, args []string, nargs int) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != nargs {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
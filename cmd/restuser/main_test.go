@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	// cli runs the command with the server URL in the environment, returning the exit code and the stdout.
+	cli := func(t *testing.T, args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		getenv := func(name string) string {
+			if name == urlEnvVar {
+				return srv.URL
+			}
+			return ""
+		}
+		code := run(context.Background(), args, getenv, &stdout, &stderr)
+		t.Log(stderr.String())
+		return code, stdout.String()
+	}
+
+	code, out := cli(t, "create", "-name", "pepe", "-password", "password123", "-country", "es")
+	require.Equal(t, exitOK, code)
+	created := decodeUser(t, out)
+	assert.Equal(t, "pepe", created.Name)
+
+	t.Run("get", func(t *testing.T) {
+		code, out := cli(t, "get", created.ID)
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, created, decodeUser(t, out))
+	})
+
+	t.Run("get yaml", func(t *testing.T) {
+		code, out := cli(t, "-o", "yaml", "get", created.ID)
+		require.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(out, "id: "+created.ID+"\n"), out)
+		assert.Contains(t, out, "name: pepe\n")
+		assert.Contains(t, out, "created_at: \""+created.CreatedAt+"\"\n")
+	})
+
+	t.Run("list table", func(t *testing.T) {
+		code, out := cli(t, "-o", "table", "list", "--country", "es")
+		require.Equal(t, exitOK, code)
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], "ID "))
+		assert.True(t, strings.HasPrefix(lines[1], created.ID+" "))
+
+		code, out = cli(t, "list", "--country", "fr")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "[]\n", out)
+
+		code, out = cli(t, "list", "--name", "pepe", "--query", "PEP")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, out, created.ID)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		code, out := cli(t, "update", "-country", "fr", created.ID)
+		require.Equal(t, exitOK, code)
+		updated := decodeUser(t, out)
+		assert.Equal(t, "fr", updated.Country)
+		assert.Equal(t, "pepe", updated.Name)
+
+		code, _ = cli(t, "update", "-updated-at", created.UpdatedAt, "-country", "pt", created.ID)
+		assert.Equal(t, exitConflict, code)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		code, _ := cli(t, "create", "-name", "pepe", "-password", "short")
+		assert.Equal(t, exitBadRequest, code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		code, out := cli(t, "delete", created.ID)
+		assert.Equal(t, exitOK, code)
+		assert.Empty(t, out)
+
+		code, _ = cli(t, "get", created.ID)
+		assert.Equal(t, exitNotFound, code)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		for _, args := range [][]string{
+			{},
+			{"unknown"},
+			{"get"},
+			{"-o", "xml", "get", created.ID},
+			{"list", "extra"},
+		} {
+			code, _ := cli(t, args...)
+			assert.Equal(t, exitUsage, code, "args: %v", args)
+		}
+	})
+
+	t.Run("url flag", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, exitUsage, run(context.Background(), []string{"list"}, noenv, &stdout, &stderr))
+		assert.Equal(t, exitOK, run(context.Background(), []string{"-url", srv.URL, "list"}, noenv, &stdout, &stderr))
+	})
+}
+
+func decodeUser(t *testing.T, out string) *restuser.User {
+	var user restuser.User
+	require.NoError(t, json.Unmarshal([]byte(out), &user))
+	return &user
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/a-faceit-candidate/restuser"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatYAML  = "yaml"
+)
+
+// printer outputs the users in the configured format.
+type printer struct {
+	stdout io.Writer
+	stderr io.Writer
+	format string
+}
+
+func newPrinter(stdout, stderr io.Writer, format string) (*printer, error) {
+	switch format {
+	case formatJSON, formatTable, formatYAML:
+		return &printer{stdout: stdout, stderr: stderr, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func (p *printer) user(user *restuser.User) error {
+	if p.format == formatTable {
+		return p.table([]restuser.User{*user})
+	}
+	return p.value(user)
+}
+
+func (p *printer) users(users []restuser.User) error {
+	if p.format == formatTable {
+		return p.table(users)
+	}
+	return p.value(users)
+}
+
+func (p *printer) value(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal JSON: %w", err)
+	}
+	if p.format == formatJSON {
+		_, err = fmt.Fprintln(p.stdout, string(data))
+		return err
+	}
+
+	// JSON is valid YAML: decoding it as a node keeps the field names and their order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("can't convert JSON to YAML: %w", err)
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(p.stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("can't marshal YAML: %w", err)
+	}
+	return enc.Close()
+}
+
+func (p *printer) table(users []restuser.User) error {
+	w := tabwriter.NewWriter(p.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tFIRST NAME\tLAST NAME\tEMAIL\tCOUNTRY\tCREATED AT\tUPDATED AT")
+	for _, u := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Name, u.FirstName, u.LastName, u.Email, u.Country, u.CreatedAt, u.UpdatedAt)
+	}
+	return w.Flush()
+}
+
+// blockStyle removes the flow style of the nodes decoded from JSON, so they're encoded as regular YAML.
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
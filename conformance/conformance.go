@@ -0,0 +1,365 @@
+// Package conformance provides a test suite checking that a running user service honours the REST API contract.
+//
+// Service implementations can run it from their own tests against a local instance:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, "http://localhost:8080")
+//	}
+package conformance
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the conformance test suite against the user service at baseURL.
+// The options are provided to the client, to configure the base path or the http client, for instance.
+// The suite creates users with random data, and deletes them when finished.
+func Run(t *testing.T, baseURL string, options ...restuser.Option) {
+	s := &suite{
+		api: restuser.New(restuser.Config{URL: baseURL}, options...),
+		newAPI: func(extra ...restuser.Option) *restuser.API {
+			return restuser.New(restuser.Config{URL: baseURL}, append(append([]restuser.Option(nil), options...), extra...)...)
+		},
+	}
+
+	t.Run("create", s.testCreate)
+	t.Run("create validation", s.testCreateValidation)
+	t.Run("update", s.testUpdate)
+	t.Run("update conflict", s.testUpdateConflict)
+	t.Run("patch", s.testPatch)
+	t.Run("delete", s.testDelete)
+	t.Run("list hides password fields", s.testListHidesPasswordFields)
+	t.Run("list filters by country", s.testListFiltersByCountry)
+	t.Run("list filters by ids", s.testListFiltersByIDs)
+	t.Run("list searches by email and name", s.testListSearchesByEmailAndName)
+	t.Run("list filters by countries and times", s.testListFiltersByCountriesAndTimes)
+	t.Run("list sorts and selects fields", s.testListSortsAndSelectsFields)
+	t.Run("get revalidates with etag", s.testGetRevalidatesWithETag)
+}
+
+type suite struct {
+	api *restuser.API
+	// newAPI creates a client with the options provided to Run and the extra ones
+	newAPI func(extra ...restuser.Option) *restuser.API
+}
+
+func (s *suite) testCreate(t *testing.T) {
+	created := s.createUser(t, randomCountry())
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.CreatedAt)
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt, "UpdatedAt should equal CreatedAt for a recently created user")
+	assert.Empty(t, created.Password, "Password should not be responded")
+	assert.NotEmpty(t, created.PasswordHash)
+	assert.NotEmpty(t, created.PasswordSalt)
+
+	got, err := s.api.GetUser(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+}
+
+func (s *suite) testCreateValidation(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := s.api.CreateUser(ctx, &restuser.User{Name: "short_password", Password: "1234567", Country: randomCountry()})
+	assert.True(t, restuser.IsBadRequest(err), "expected bad request on short password, got %v", err)
+
+	_, err = s.api.CreateUser(ctx, &restuser.User{ID: "c3e11b46-109c-11eb-adc1-0242ac120002", Password: "password123", Country: randomCountry()})
+	assert.True(t, restuser.IsBadRequest(err), "expected bad request on non-empty ID, got %v", err)
+}
+
+func (s *suite) testUpdate(t *testing.T) {
+	created := s.createUser(t, randomCountry())
+
+	toUpdate := *created
+	toUpdate.Name = "conformance_updated"
+	updated, err := s.api.UpdateUser(context.Background(), &toUpdate)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt, "CreatedAt should not be updated")
+	assert.Equal(t, "conformance_updated", updated.Name)
+	assert.Equal(t, created.PasswordHash, updated.PasswordHash, "password should not be updated when empty")
+
+	got, err := s.api.GetUser(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, got)
+}
+
+func (s *suite) testUpdateConflict(t *testing.T) {
+	created := s.createUser(t, randomCountry())
+
+	toUpdate := *created
+	toUpdate.Name = "conformance_updated"
+	_, err := s.api.UpdateUser(context.Background(), &toUpdate)
+	require.NoError(t, err)
+
+	stale := *created
+	stale.Name = "conformance_stale"
+	_, err = s.api.UpdateUser(context.Background(), &stale)
+	assert.True(t, restuser.IsConflict(err), "expected conflict on stale UpdatedAt, got %v", err)
+}
+
+func (s *suite) testPatch(t *testing.T) {
+	ctx := context.Background()
+	created := s.createUser(t, randomCountry())
+
+	patched, err := s.api.PatchUser(ctx, created.ID, restuser.UserPatch{Name: restuser.String("conformance_patched")}, created.UpdatedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "conformance_patched", patched.Name)
+	assert.Equal(t, created.Email, patched.Email, "fields not provided should not be patched")
+	assert.Equal(t, created.PasswordHash, patched.PasswordHash, "password should not be patched when not provided")
+
+	got, err := s.api.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, patched, got)
+
+	_, err = s.api.PatchUser(ctx, created.ID, restuser.UserPatch{Name: restuser.String("conformance_stale")}, created.UpdatedAt)
+	assert.True(t, restuser.IsPreconditionFailed(err), "expected precondition failed on stale If-Match, got %v", err)
+}
+
+func (s *suite) testDelete(t *testing.T) {
+	ctx := context.Background()
+	created := s.createUser(t, randomCountry())
+
+	require.NoError(t, s.api.DeleteUser(ctx, created.ID))
+
+	_, err := s.api.GetUser(ctx, created.ID)
+	assert.True(t, restuser.IsNotFound(err), "expected not found after delete, got %v", err)
+
+	err = s.api.DeleteUser(ctx, created.ID)
+	assert.True(t, restuser.IsNotFound(err), "expected not found deleting twice, got %v", err)
+
+	toUpdate := *created
+	_, err = s.api.UpdateUser(ctx, &toUpdate)
+	assert.True(t, restuser.IsNotFound(err), "expected not found updating after delete, got %v", err)
+}
+
+func (s *suite) testListHidesPasswordFields(t *testing.T) {
+	country := randomCountry()
+	created := s.createUser(t, country)
+
+	users := s.listUsers(t, restuser.ListUsersParams{Country: country})
+	require.Contains(t, ids(users), created.ID)
+	for _, user := range users {
+		assert.Empty(t, user.Password, "Password should not be listed")
+		assert.Empty(t, user.PasswordHash, "PasswordHash should not be listed")
+		assert.Empty(t, user.PasswordSalt, "PasswordSalt should not be listed")
+	}
+}
+
+func (s *suite) testListFiltersByCountry(t *testing.T) {
+	country, otherCountry := randomCountry(), randomCountry()
+	for otherCountry == country {
+		otherCountry = randomCountry()
+	}
+	first := s.createUser(t, country)
+	second := s.createUser(t, country)
+	other := s.createUser(t, otherCountry)
+
+	users := s.listUsers(t, restuser.ListUsersParams{Country: country})
+	for _, user := range users {
+		assert.Equal(t, country, user.Country)
+	}
+	assert.Contains(t, ids(users), first.ID)
+	assert.Contains(t, ids(users), second.ID)
+	assert.NotContains(t, ids(users), other.ID)
+}
+
+func (s *suite) testListFiltersByIDs(t *testing.T) {
+	country := randomCountry()
+	first := s.createUser(t, country)
+	second := s.createUser(t, country)
+	other := s.createUser(t, country)
+
+	users := s.listUsers(t, restuser.ListUsersParams{IDs: []string{first.ID, second.ID}, Limit: 1})
+	assert.ElementsMatch(t, []string{first.ID, second.ID}, ids(users))
+	assert.NotContains(t, ids(users), other.ID)
+}
+
+func (s *suite) testListSearchesByEmailAndName(t *testing.T) {
+	country := randomCountry()
+	created := s.createUser(t, country)
+	other := s.createUser(t, country)
+
+	for _, params := range []restuser.ListUsersParams{
+		{Email: created.Email},
+		{Name: created.Name},
+		{NamePrefix: created.Name[:len(created.Name)-1]},
+		{Country: country, FirstName: created.FirstName, LastName: created.LastName, Query: strings.ToUpper(created.Name)},
+	} {
+		users := s.listUsers(t, params)
+		assert.Contains(t, ids(users), created.ID, "expected user to be listed with %+v", params)
+		if params.Country == "" {
+			assert.NotContains(t, ids(users), other.ID, "expected other user not to be listed with %+v", params)
+		}
+	}
+}
+
+func (s *suite) testListFiltersByCountriesAndTimes(t *testing.T) {
+	country, otherCountry := randomCountry(), randomCountry()
+	for otherCountry == country {
+		otherCountry = randomCountry()
+	}
+	first := s.createUser(t, country)
+	second := s.createUserAfter(t, otherCountry, first)
+	created := []string{first.ID, second.ID}
+
+	// the users are filtered by their IDs too, since other users could be in the random countries
+	users := s.listUsers(t, restuser.ListUsersParams{IDs: created, Countries: []string{country, otherCountry}})
+	assert.ElementsMatch(t, created, ids(users))
+	users = s.listUsers(t, restuser.ListUsersParams{IDs: created, Countries: []string{otherCountry}})
+	assert.Equal(t, []string{second.ID}, ids(users))
+
+	firstCreatedAt, secondCreatedAt := parseTime(t, first.CreatedAt), parseTime(t, second.CreatedAt)
+	users = s.listUsers(t, restuser.ListUsersParams{IDs: created, CreatedAfter: firstCreatedAt, CreatedBefore: secondCreatedAt.Add(time.Second)})
+	assert.Equal(t, []string{second.ID}, ids(users))
+	users = s.listUsers(t, restuser.ListUsersParams{IDs: created, CreatedAfter: firstCreatedAt.Add(-time.Second), CreatedBefore: secondCreatedAt})
+	assert.Equal(t, []string{first.ID}, ids(users))
+	users = s.listUsers(t, restuser.ListUsersParams{IDs: created, UpdatedAfter: firstCreatedAt})
+	assert.Equal(t, []string{second.ID}, ids(users), "only the last user was updated after the first one was created")
+}
+
+func (s *suite) testListSortsAndSelectsFields(t *testing.T) {
+	country := randomCountry()
+	first := s.createUser(t, country)
+	second := s.createUserAfter(t, country, first)
+	third := s.createUserAfter(t, country, second)
+
+	users := s.listUsers(t, restuser.ListUsersParams{
+		IDs:    []string{first.ID, second.ID, third.ID},
+		Sort:   []restuser.SortKey{{Field: "created_at", Descending: true}},
+		Fields: []string{"id", "country"},
+		Limit:  1,
+	})
+	assert.Equal(t, []string{third.ID, second.ID, first.ID}, ids(users))
+	for _, user := range users {
+		assert.Equal(t, restuser.User{ID: user.ID, Country: country}, user, "only the selected fields should be listed")
+	}
+}
+
+func (s *suite) testGetRevalidatesWithETag(t *testing.T) {
+	ctx := context.Background()
+	created := s.createUser(t, randomCountry())
+
+	var statuses []int
+	api := s.newAPI(
+		restuser.WithCache(restuser.NewLRUCache(1, 0)),
+		restuser.WithMiddleware(func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+			return func(ctx context.Context) (*http.Response, error) {
+				resp, err := next(ctx)
+				if err == nil {
+					statuses = append(statuses, resp.StatusCode)
+				}
+				return resp, err
+			}
+		}),
+	)
+	for i := 0; i < 2; i++ {
+		got, err := api.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, statuses, "expected 304 revalidating with the ETag")
+
+	toUpdate := *created
+	toUpdate.Name = "conformance_updated"
+	updated, err := s.api.UpdateUser(ctx, &toUpdate)
+	require.NoError(t, err)
+	got, err := api.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, got, "ETag should change when the user is updated")
+}
+
+// createUser creates a user with random data in the given country, which is deleted when the test finishes.
+func (s *suite) createUser(t *testing.T, country string) *restuser.User {
+	t.Helper()
+	suffix := randomString(8)
+	created, err := s.api.CreateUser(context.Background(), &restuser.User{
+		FirstName: "Conformance",
+		LastName:  "Test",
+		Name:      "conformance_" + suffix,
+		Email:     fmt.Sprintf("conformance_%s@example.com", suffix),
+		Password:  "password_" + suffix,
+		Country:   country,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := s.api.DeleteUser(context.Background(), created.ID)
+		if err != nil && !restuser.IsNotFound(err) {
+			t.Errorf("can't delete user %s: %s", created.ID, err)
+		}
+	})
+	return created
+}
+
+// createUserAfter creates a user like createUser, created later than the previous one.
+// Users are created again until their CreatedAt differs, since services can stamp them with a precision of seconds.
+func (s *suite) createUserAfter(t *testing.T, country string, previous *restuser.User) *restuser.User {
+	t.Helper()
+	previousCreatedAt := parseTime(t, previous.CreatedAt)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		created := s.createUser(t, country)
+		if parseTime(t, created.CreatedAt).After(previousCreatedAt) {
+			return created
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("user created at %s, not later than the previous one created at %s", created.CreatedAt, previous.CreatedAt)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// parseTime parses the RFC3339 timestamps of the users.
+func parseTime(t *testing.T, timestamp string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339Nano, timestamp)
+	require.NoError(t, err, "timestamps should be RFC3339")
+	return parsed
+}
+
+// listUsers lists all the pages of users matching the params.
+func (s *suite) listUsers(t *testing.T, params restuser.ListUsersParams) []restuser.User {
+	t.Helper()
+	var users []restuser.User
+	it := s.api.IterateUsers(params)
+	for it.Next(context.Background()) {
+		users = append(users, it.User())
+	}
+	require.NoError(t, it.Err())
+	return users
+}
+
+func ids(users []restuser.User) []string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+// randomCountry returns a random two lowercase letters code, most likely not used by other users of the service.
+func randomCountry() string {
+	return randomString(2)
+}
+
+func randomString(length int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Errorf("can't read random bytes: %w", err))
+	}
+	for i := range b {
+		b[i] = letters[int(b[i])%len(letters)]
+	}
+	return string(b)
+}
@@ -0,0 +1,15 @@
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser/conformance"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+)
+
+func TestRun(t *testing.T) {
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	conformance.Run(t, srv.URL)
+}
@@ -35,7 +35,7 @@ var doc = `{
     "paths": {
         "/users": {
             "get": {
-                "description": "List users, can be filtered by country code.\nThis operation does not return the PasswordHash and PasswordSalt fields for security reasons.",
+                "description": "List users, can be filtered by country codes, IDs, email, names, a free-text query, or creation and update times.\nThis operation does not return the PasswordHash and PasswordSalt fields for security reasons.\nResults can be paginated using the ` + "`" + `limit` + "`" + ` parameter: when there are more results,\nthe ` + "`" + `Link` + "`" + ` header contains the URL of the next page with ` + "`" + `rel=\"next\"` + "`" + `, including the ` + "`" + `cursor` + "`" + ` parameter.\nUsers are sorted by ` + "`" + `id` + "`" + ` unless ` + "`" + `sort` + "`" + ` is provided, and ties are sorted by ` + "`" + `id` + "`" + ` too.",
                 "produces": [
                     "application/json"
                 ],
@@ -43,10 +43,105 @@ var doc = `{
                 "operationId": "list-users",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "filter by country code",
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "filter by country codes, can be provided several times",
                         "name": "country",
                         "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "filter by user IDs, can be provided several times",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by nickname",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by nickname prefix",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by first name",
+                        "name": "first_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by last name",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "free-text search, case-insensitive, on the nickname, first name, last name and email",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "filter by users created after the RFC3339 time",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "filter by users created before the RFC3339 time",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "filter by users updated after the RFC3339 time",
+                        "name": "updated_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated fields to sort by, prefixed with - for descending order, like created_at,-name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated fields to respond, like id,name,country, the rest are omitted",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of users to return, the service can return fewer",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "opaque cursor to retrieve the next page, obtained from the Link header",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -57,6 +152,12 @@ var doc = `{
                             "items": {
                                 "$ref": "#/definitions/restuser.User"
                             }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "URL of the next page, with rel next, absent on the last page"
+                            }
                         }
                     },
                     "400": {
@@ -65,11 +166,35 @@ var doc = `{
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
                     }
                 }
             },
@@ -96,17 +221,42 @@ var doc = `{
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
                     }
                 }
             }
         },
         "/users/{id}": {
             "get": {
+                "description": "The ` + "`" + `ETag` + "`" + ` header can be provided as ` + "`" + `If-None-Match` + "`" + ` to respond 304 if the user was not modified.",
                 "produces": [
                     "application/json"
                 ],
@@ -119,6 +269,12 @@ var doc = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the user previously retrieved",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -126,19 +282,52 @@ var doc = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/restuser.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the user, its updated_at enclosed in double quotes, as a strong validator"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "If the ETag provided as If-None-Match matches"
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
                     }
                 }
             },
@@ -166,6 +355,12 @@ var doc = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/restuser.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the user, its updated_at enclosed in double quotes, as a strong validator"
+                            }
                         }
                     },
                     "400": {
@@ -186,11 +381,35 @@ var doc = `{
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
                     }
                 }
             },
@@ -217,17 +436,440 @@ var doc = `{
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "The body is a JSON Merge Patch (RFC 7396): only the fields provided are modified, and null clears them.\nThe ` + "`" + `id` + "`" + `, ` + "`" + `created_at` + "`" + `, ` + "`" + `updated_at` + "`" + `, ` + "`" + `password_hash` + "`" + ` and ` + "`" + `password_salt` + "`" + ` fields can't be modified,\nand the ` + "`" + `password` + "`" + ` can't be cleared.",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Partially update a user with the given ID.",
+                "operationId": "patch-user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, to modify it only if it was not modified since it was retrieved. Compared using the strong comparison",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Fields to modify",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/restuser.UserPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the user, its updated_at enclosed in double quotes, as a strong validator"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "If If-Match is not provided and the user keeps being updated concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "If the ETag provided as If-Match doesn't match, or the user was updated concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "If the body is not provided as application/merge-patch+json",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users:batchCreate": {
+            "post": {
+                "description": "Each result has the created user or the error of the user in the same position of the request.\nIn ` + "`" + `all_or_nothing` + "`" + ` mode, no user is created if any of them fails,\nand the results of the ones that didn't fail have the 424 status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create several users.",
+                "operationId": "batch-create-users",
+                "parameters": [
+                    {
+                        "description": "Users to create, at most 100",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/restuser.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users:batchDelete": {
+            "post": {
+                "description": "Each result has the error, if any, of the ID in the same position of the request.\nIn ` + "`" + `all_or_nothing` + "`" + ` mode, no user is deleted if any of them fails,\nand the results of the ones that didn't fail have the 424 status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete several users by their IDs.",
+                "operationId": "batch-delete-users",
+                "parameters": [
+                    {
+                        "description": "IDs of the users, at most 100",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/restuser.BatchDeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users:batchGet": {
+            "post": {
+                "description": "Each result has the user or the error of the ID in the same position of the request.\nThis operation does not return the PasswordHash and PasswordSalt fields for security reasons.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve several users by their IDs.",
+                "operationId": "batch-get-users",
+                "parameters": [
+                    {
+                        "description": "IDs of the users, at most 100",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/restuser.BatchGetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/restuser.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable, retry after the time provided in the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/restuser.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds or HTTP date after which the request can be retried"
+                            }
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "restuser.BatchCreateRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "Mode is ` + "`" + `best_effort` + "`" + ` if not provided.",
+                    "type": "string",
+                    "enum": [
+                        "best_effort",
+                        "all_or_nothing"
+                    ]
+                },
+                "users": {
+                    "description": "Users to be created, with the same restrictions as the users created individually.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/restuser.User"
+                    }
+                }
+            }
+        },
+        "restuser.BatchDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "c3e11b46-109c-11eb-adc1-0242ac120002"
+                    ]
+                },
+                "mode": {
+                    "description": "Mode is ` + "`" + `best_effort` + "`" + ` if not provided.",
+                    "type": "string",
+                    "enum": [
+                        "best_effort",
+                        "all_or_nothing"
+                    ]
+                }
+            }
+        },
+        "restuser.BatchGetRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "c3e11b46-109c-11eb-adc1-0242ac120002"
+                    ]
+                }
+            }
+        },
+        "restuser.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "description": "Results has the result of each item of the request, in the same order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/restuser.BatchResult"
+                    }
+                }
+            }
+        },
+        "restuser.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is provided for the failed items.",
+                    "$ref": "#/definitions/restuser.ErrorResponse"
+                },
+                "status": {
+                    "description": "Status is the status code that would be responded if the item was requested individually.\nWhen an ` + "`" + `all_or_nothing` + "`" + ` batch fails, the items that didn't fail have the 424 Failed Dependency status.",
+                    "type": "integer",
+                    "example": 200
+                },
+                "user": {
+                    "description": "User is provided for the successful items of batch get and batch create operations.\nPasswordHash and PasswordSalt are not provided by batch get.",
+                    "$ref": "#/definitions/restuser.User"
+                }
+            }
+        },
         "restuser.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -300,6 +942,37 @@ var doc = `{
                     "example": "2006-01-02T15:04:05Z"
                 }
             }
+        },
+        "restuser.UserPatch": {
+            "type": "object",
+            "properties": {
+                "country": {
+                    "type": "string",
+                    "example": "es"
+                },
+                "email": {
+                    "type": "string",
+                    "format": "email",
+                    "example": "john@colega.eu"
+                },
+                "first_name": {
+                    "type": "string",
+                    "example": "John"
+                },
+                "last_name": {
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "john_doe87"
+                },
+                "password": {
+                    "description": "Password can't be cleared, and it should be at least 8 characters long.",
+                    "type": "string",
+                    "format": "password"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,167 @@
+package restuser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sentinel errors matching, through errors.Is, the errors returned by client methods for the documented statuses.
+var (
+	ErrBadRequest = errors.New("bad request")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrInternal   = errors.New("internal error")
+
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrServiceUnavailable = errors.New("service unavailable")
+)
+
+// unexpectedStatusBodyLimit is the maximum amount of bytes of the body kept in an UnexpectedStatusError.
+const unexpectedStatusBodyLimit = 1024
+
+// Error is returned by client methods when API returns a non-success status code which is documented.
+type Error struct {
+	StatusCode int
+	Response   *ErrorResponse
+}
+
+func (e Error) Error() string {
+	if e.Response != nil {
+		return fmt.Sprintf("userservice responded %d: %s", e.StatusCode, e.Response.Message)
+	}
+	return fmt.Sprintf("userservice responded %d", e.StatusCode)
+}
+
+// Is allows matching the sentinel errors with errors.Is.
+func (e Error) Is(target error) bool {
+	return matchesStatus(target, e.StatusCode)
+}
+
+// UnexpectedStatusError is returned by client methods when API returns a status code which is not documented.
+type UnexpectedStatusError struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the beginning of the response body, truncated to 1024 bytes.
+	Body []byte
+}
+
+func (e UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("received unexpected status code %d", e.StatusCode)
+}
+
+// Is allows matching the sentinel errors with errors.Is.
+func (e UnexpectedStatusError) Is(target error) bool {
+	return matchesStatus(target, e.StatusCode)
+}
+
+// RetryAfterError is returned by client methods when API responds 429 or 503,
+// asking the client to slow down or to wait until the service is available again.
+type RetryAfterError struct {
+	StatusCode int
+	// Response is nil when the body is not an ErrorResponse, as it can be provided by a proxy.
+	Response *ErrorResponse
+	// RetryAfter is the time to wait before performing the request again, parsed from the Retry-After header.
+	// It's zero if the header was not provided or couldn't be parsed.
+	RetryAfter time.Duration
+}
+
+func (e RetryAfterError) Error() string {
+	msg := fmt.Sprintf("userservice responded %d", e.StatusCode)
+	if e.Response != nil {
+		msg += ": " + e.Response.Message
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %s", e.RetryAfter)
+	}
+	return msg
+}
+
+// Is allows matching the sentinel errors with errors.Is.
+func (e RetryAfterError) Is(target error) bool {
+	return matchesStatus(target, e.StatusCode)
+}
+
+// parseRetryAfter parses the Retry-After header, that can be provided either as seconds or as an HTTP date.
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// matchesStatus reports whether target is the sentinel error representing the status code.
+// Targets are compared one by one instead of being looked up in a map, since they can be of unhashable types.
+func matchesStatus(target error, statusCode int) bool {
+	switch target {
+	case ErrBadRequest:
+		return statusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return statusCode == http.StatusNotFound
+	case ErrConflict:
+		return statusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return statusCode == http.StatusPreconditionFailed
+	case ErrInternal:
+		return statusCode == http.StatusInternalServerError
+	case ErrTooManyRequests:
+		return statusCode == http.StatusTooManyRequests
+	case ErrServiceUnavailable:
+		return statusCode == http.StatusServiceUnavailable
+	default:
+		return false
+	}
+}
+
+// IsBadRequest reports whether err is caused by the API responding 400.
+func IsBadRequest(err error) bool {
+	return errors.Is(err, ErrBadRequest)
+}
+
+// IsNotFound reports whether err is caused by the API responding 404.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err is caused by the API responding 409.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsInternal reports whether err is caused by the API responding 500.
+func IsInternal(err error) bool {
+	return errors.Is(err, ErrInternal)
+}
+
+// IsPreconditionFailed reports whether err is caused by the API responding 412.
+func IsPreconditionFailed(err error) bool {
+	return errors.Is(err, ErrPreconditionFailed)
+}
+
+// IsTooManyRequests reports whether err is caused by the API responding 429.
+func IsTooManyRequests(err error) bool {
+	return errors.Is(err, ErrTooManyRequests)
+}
+
+// IsServiceUnavailable reports whether err is caused by the API responding 503.
+func IsServiceUnavailable(err error) bool {
+	return errors.Is(err, ErrServiceUnavailable)
+}
@@ -0,0 +1,152 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError_Is(t *testing.T) {
+	for _, tc := range []struct {
+		statusCode int
+		sentinel   error
+		is         func(error) bool
+	}{
+		{statusCode: http.StatusBadRequest, sentinel: restuser.ErrBadRequest, is: restuser.IsBadRequest},
+		{statusCode: http.StatusNotFound, sentinel: restuser.ErrNotFound, is: restuser.IsNotFound},
+		{statusCode: http.StatusConflict, sentinel: restuser.ErrConflict, is: restuser.IsConflict},
+		{statusCode: http.StatusPreconditionFailed, sentinel: restuser.ErrPreconditionFailed, is: restuser.IsPreconditionFailed},
+		{statusCode: http.StatusInternalServerError, sentinel: restuser.ErrInternal, is: restuser.IsInternal},
+		{statusCode: http.StatusTooManyRequests, sentinel: restuser.ErrTooManyRequests, is: restuser.IsTooManyRequests},
+		{statusCode: http.StatusServiceUnavailable, sentinel: restuser.ErrServiceUnavailable, is: restuser.IsServiceUnavailable},
+	} {
+		t.Run(http.StatusText(tc.statusCode), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", restuser.Error{StatusCode: tc.statusCode})
+			assert.True(t, errors.Is(err, tc.sentinel))
+			assert.True(t, tc.is(err))
+
+			unexpected := restuser.UnexpectedStatusError{StatusCode: tc.statusCode}
+			assert.True(t, errors.Is(unexpected, tc.sentinel))
+
+			retryAfter := restuser.RetryAfterError{StatusCode: tc.statusCode}
+			assert.True(t, errors.Is(retryAfter, tc.sentinel))
+
+			other := restuser.Error{StatusCode: http.StatusTeapot}
+			assert.False(t, errors.Is(other, tc.sentinel))
+			assert.False(t, tc.is(other))
+			assert.False(t, tc.is(errors.New("something else")))
+		})
+	}
+}
+
+func TestError_Is_UnhashableTarget(t *testing.T) {
+	target := restuser.UnexpectedStatusError{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: []byte("not found")}
+	for _, err := range []error{
+		restuser.Error{StatusCode: http.StatusNotFound},
+		restuser.UnexpectedStatusError{StatusCode: http.StatusNotFound},
+		restuser.RetryAfterError{StatusCode: http.StatusNotFound},
+	} {
+		assert.NotPanics(t, func() { assert.False(t, errors.Is(err, target)) })
+	}
+}
+
+func TestUnexpectedStatusError(t *testing.T) {
+	longBody := strings.Repeat("a", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Some-Header", "some value")
+		rw.WriteHeader(http.StatusBadGateway)
+		_, _ = rw.Write([]byte(longBody))
+	}))
+	defer srv.Close()
+
+	api := restuser.New(restuser.Config{URL: srv.URL})
+	_, err := api.GetUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+
+	var unexpected restuser.UnexpectedStatusError
+	require.True(t, errors.As(err, &unexpected))
+	assert.Equal(t, http.StatusBadGateway, unexpected.StatusCode)
+	assert.Equal(t, "some value", unexpected.Header.Get("X-Some-Header"))
+	assert.Equal(t, longBody[:1024], string(unexpected.Body))
+	assert.EqualError(t, err, "received unexpected status code 502")
+}
+
+func TestRetryAfterError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		header   string
+		body     string
+		expected restuser.RetryAfterError
+		message  string
+	}{
+		{
+			name:     "seconds",
+			status:   http.StatusTooManyRequests,
+			header:   "120",
+			body:     `{"message":"slow down"}`,
+			expected: restuser.RetryAfterError{StatusCode: http.StatusTooManyRequests, Response: &restuser.ErrorResponse{Message: "slow down"}, RetryAfter: 2 * time.Minute},
+			message:  "userservice responded 429: slow down, retry after 2m0s",
+		},
+		{
+			name:     "date in the past",
+			status:   http.StatusServiceUnavailable,
+			header:   "Wed, 21 Oct 2015 07:28:00 GMT",
+			body:     `{"message":"maintenance"}`,
+			expected: restuser.RetryAfterError{StatusCode: http.StatusServiceUnavailable, Response: &restuser.ErrorResponse{Message: "maintenance"}},
+			message:  "userservice responded 503: maintenance",
+		},
+		{
+			name:     "no header and body from a proxy",
+			status:   http.StatusServiceUnavailable,
+			body:     `<html>Service Unavailable</html>`,
+			expected: restuser.RetryAfterError{StatusCode: http.StatusServiceUnavailable},
+			message:  "userservice responded 503",
+		},
+		{
+			name:     "invalid header",
+			status:   http.StatusTooManyRequests,
+			header:   "soon",
+			expected: restuser.RetryAfterError{StatusCode: http.StatusTooManyRequests},
+			message:  "userservice responded 429",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				if tc.header != "" {
+					rw.Header().Set("Retry-After", tc.header)
+				}
+				rw.WriteHeader(tc.status)
+				_, _ = rw.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			api := restuser.New(restuser.Config{URL: srv.URL})
+			_, err := api.GetUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+			assert.Equal(t, tc.expected, err)
+			assert.EqualError(t, err, tc.message)
+		})
+	}
+
+	t.Run("date in the future", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL})
+		err := api.DeleteUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+		var retryAfter restuser.RetryAfterError
+		require.True(t, errors.As(err, &retryAfter))
+		assert.InDelta(t, time.Hour, retryAfter.RetryAfter, float64(2*time.Second))
+	})
+}
@@ -0,0 +1,270 @@
+package restuser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// hedgingSamples is the amount of latencies kept for each operation to calculate the p95.
+	hedgingSamples = 200
+	// minHedgingSamples is the amount of latencies observed before hedging based on the p95.
+	minHedgingSamples = 20
+	// maxHedgingTokens caps the hedges that can be accumulated by the budget while no hedges are needed.
+	maxHedgingTokens = 10
+)
+
+// HedgingPolicy configures how the idempotent reads are hedged.
+// Zero values are replaced by the ones from DefaultHedgingPolicy when provided to WithHedging.
+type HedgingPolicy struct {
+	// Delay is the time waited for a response before sending a hedged request.
+	// If it's zero, the p95 latency observed for the operation is used instead,
+	// and requests are not hedged until enough latencies are observed.
+	Delay time.Duration
+	// MaxHedges is the maximum amount of hedged requests sent for each attempt, besides the original one.
+	MaxHedges int
+	// Budget is the maximum ratio of hedged requests to calls, 0.1 allows one hedged request each ten calls.
+	Budget float64
+}
+
+// DefaultHedgingPolicy returns the HedgingPolicy used to complete the zero values of the provided one.
+func DefaultHedgingPolicy() HedgingPolicy {
+	return HedgingPolicy{
+		MaxHedges: 1,
+		Budget:    0.1,
+	}
+}
+
+// WithHedging configures the API to hedge GetUser and ListUsers: when the response takes longer than the delay,
+// the same request is sent again, and the first response received is used, cancelling the other requests.
+// Hedging reduces the tail latency at the cost of increasing the load of the service, which is capped by the budget.
+// Each attempt of the RetryPolicy is hedged independently.
+func WithHedging(policy HedgingPolicy) Option {
+	defaults := DefaultHedgingPolicy()
+	if policy.MaxHedges == 0 {
+		policy.MaxHedges = defaults.MaxHedges
+	}
+	if policy.Budget == 0 {
+		policy.Budget = defaults.Budget
+	}
+	return func(api *API) {
+		api.hedger = &hedger{policy: policy, latencies: map[string]*latencies{}}
+	}
+}
+
+// HedgingStats are the counters of the hedged calls, to check how often hedging wins.
+type HedgingStats struct {
+	// Calls is the amount of attempts that could have been hedged.
+	Calls int64
+	// Hedges is the amount of hedged requests sent.
+	Hedges int64
+	// Wins is the amount of hedged requests whose response was used, because it arrived before the original one.
+	Wins int64
+}
+
+// HedgingStats returns the counters of the hedged calls since the API was created.
+// They're all zero if WithHedging was not configured.
+func (a *API) HedgingStats() HedgingStats {
+	if a.hedger == nil {
+		return HedgingStats{}
+	}
+	return HedgingStats{
+		Calls:  atomic.LoadInt64(&a.hedger.calls),
+		Hedges: atomic.LoadInt64(&a.hedger.hedges),
+		Wins:   atomic.LoadInt64(&a.hedger.wins),
+	}
+}
+
+type hedger struct {
+	calls, hedges, wins int64
+
+	policy HedgingPolicy
+
+	mu        sync.Mutex
+	tokens    float64
+	latencies map[string]*latencies
+}
+
+// applies reports whether the operation is hedged, only idempotent reads are.
+func (h *hedger) applies(op Operation) bool {
+	return h != nil && op.Idempotent && op.Method == http.MethodGet
+}
+
+// delay returns the time to wait for a response before hedging, or false if requests shouldn't be hedged yet.
+func (h *hedger) delay(op Operation) (time.Duration, bool) {
+	if h.policy.Delay > 0 {
+		return h.policy.Delay, true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l, ok := h.latencies[op.ID]
+	if !ok || l.count < minHedgingSamples {
+		return 0, false
+	}
+	return l.percentile(0.95), true
+}
+
+// call is called for each hedgeable attempt, it adds to the budget.
+func (h *hedger) call() {
+	atomic.AddInt64(&h.calls, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens += h.policy.Budget
+	if h.tokens > maxHedgingTokens {
+		h.tokens = maxHedgingTokens
+	}
+}
+
+// hedge reports whether the budget allows sending a hedged request, consuming from it.
+func (h *hedger) hedge() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	atomic.AddInt64(&h.hedges, 1)
+	return true
+}
+
+func (h *hedger) observe(op Operation, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l, ok := h.latencies[op.ID]
+	if !ok {
+		l = &latencies{}
+		h.latencies[op.ID] = l
+	}
+	l.add(latency)
+}
+
+// latencies is a ring buffer of the last observed latencies.
+type latencies struct {
+	samples [hedgingSamples]time.Duration
+	count   int
+}
+
+func (l *latencies) add(latency time.Duration) {
+	l.samples[l.count%hedgingSamples] = latency
+	l.count++
+}
+
+func (l *latencies) percentile(p float64) time.Duration {
+	n := l.count
+	if n > hedgingSamples {
+		n = hedgingSamples
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, l.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(n-1))]
+}
+
+// sendHedged performs a single attempt of the operation, sending copies of the request when the response is late.
+// The first response received is returned, or the last error if all of them failed.
+// Hedged requests are built by prepare, so they can be sent to a different endpoint.
+func (a *API) sendHedged(ctx context.Context, op Operation, e *endpoint, req *http.Request,
+	prepare func(context.Context) (*endpoint, *http.Request, error), attempt int) (*http.Response, error) {
+	type result struct {
+		resp    *http.Response
+		err     error
+		hedged  bool
+		latency time.Duration
+		// index of the request, to find its cancel function
+		index int
+	}
+	h := a.hedger
+	h.call()
+
+	results := make(chan result, 1+h.policy.MaxHedges)
+	var cancels []context.CancelFunc
+	send := func(reqCtx context.Context, e *endpoint, req *http.Request, hedged bool) {
+		index := len(cancels) - 1
+		go func() {
+			start := time.Now()
+			resp, err := a.send(reqCtx, op, e, req, attempt)
+			results <- result{resp: resp, err: err, hedged: hedged, latency: time.Since(start), index: index}
+		}()
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	cancels = append(cancels, cancel)
+	send(reqCtx, e, req.WithContext(reqCtx), false)
+	pending, hedges := 1, 0
+	var timeout <-chan time.Time
+	if delay, ok := h.delay(op); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var last result
+	for pending > 0 {
+		select {
+		case <-timeout:
+			timeout = nil
+			if !h.hedge() {
+				continue
+			}
+			reqCtx, cancel := context.WithCancel(ctx)
+			cancels = append(cancels, cancel)
+			e, req, err := prepare(reqCtx)
+			if err != nil {
+				continue
+			}
+			send(reqCtx, e, req, true)
+			pending++
+			hedges++
+			if delay, ok := h.delay(op); ok && hedges < h.policy.MaxHedges {
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+		case last = <-results:
+			pending--
+			if last.err != nil {
+				continue
+			}
+			h.observe(op, last.latency)
+			if last.hedged {
+				atomic.AddInt64(&h.wins, 1)
+			}
+			// cancel the rest and discard their responses, the winner is cancelled when its body is closed
+			for i, cancel := range cancels {
+				if i != last.index {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for ; pending > 0; pending-- {
+					if r := <-results; r.resp != nil {
+						drainAndClose(r.resp.Body)
+					}
+				}
+			}(pending)
+			last.resp.Body = &cancelOnClose{ReadCloser: last.resp.Body, cancel: cancels[last.index]}
+			return last.resp, nil
+		}
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return nil, last.err
+}
+
+// cancelOnClose cancels the context of the request when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
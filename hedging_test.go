@@ -0,0 +1,93 @@
+package restuser_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHedging(t *testing.T) {
+	someUser := &restuser.User{ID: "c3e11b46-109c-11eb-adc1-0242ac120002", Name: "pepe"}
+
+	// stallingServer stalls the requests for which stall returns true until they're cancelled, reporting it.
+	stallingServer := func(t *testing.T, stall func(call int64) bool, cancelled chan<- struct{}) *httptest.Server {
+		var calls int64
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if stall(atomic.AddInt64(&calls, 1)) {
+				select {
+				case <-req.Context().Done():
+					cancelled <- struct{}{}
+				case <-time.After(time.Second):
+				}
+				return
+			}
+			assert.NoError(t, json.NewEncoder(rw).Encode(someUser))
+		}))
+	}
+
+	t.Run("hedge wins and the original request is cancelled", func(t *testing.T) {
+		cancelled := make(chan struct{}, 1)
+		srv := stallingServer(t, func(call int64) bool { return call == 1 }, cancelled)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithHedging(restuser.HedgingPolicy{Delay: 10 * time.Millisecond, Budget: 1}))
+		user, err := api.GetUser(context.Background(), someUser.ID)
+		require.NoError(t, err)
+		assert.Equal(t, someUser, user)
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("original request was not cancelled")
+		}
+		assert.Equal(t, restuser.HedgingStats{Calls: 1, Hedges: 1, Wins: 1}, api.HedgingStats())
+	})
+
+	t.Run("budget caps the hedges", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			assert.NoError(t, json.NewEncoder(rw).Encode(someUser))
+		}))
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithHedging(restuser.HedgingPolicy{Delay: time.Millisecond, Budget: 0.5}))
+		for i := 0; i < 4; i++ {
+			_, err := api.GetUser(context.Background(), someUser.ID)
+			require.NoError(t, err)
+		}
+		stats := api.HedgingStats()
+		assert.Equal(t, int64(4), stats.Calls)
+		assert.Equal(t, int64(2), stats.Hedges)
+	})
+
+	t.Run("hedges after observing the p95 latency", func(t *testing.T) {
+		cancelled := make(chan struct{}, 1)
+		srv := stallingServer(t, func(call int64) bool { return call == 21 }, cancelled)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithHedging(restuser.HedgingPolicy{Budget: 1}))
+		for i := 0; i < 21; i++ {
+			_, err := api.GetUser(context.Background(), someUser.ID)
+			require.NoError(t, err)
+		}
+		<-cancelled
+		assert.Equal(t, restuser.HedgingStats{Calls: 21, Hedges: 1, Wins: 1}, api.HedgingStats())
+	})
+
+	t.Run("does not hedge writes", func(t *testing.T) {
+		srv := stallingServer(t, func(int64) bool { return false }, nil)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithHedging(restuser.HedgingPolicy{Delay: time.Nanosecond, Budget: 1}))
+		_ = api.DeleteUser(context.Background(), someUser.ID)
+		assert.Equal(t, restuser.HedgingStats{}, api.HedgingStats())
+	})
+}
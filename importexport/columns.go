@@ -0,0 +1,161 @@
+// Package importexport exports the users of the user service to JSONL or CSV, and imports them back.
+package importexport
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+// Format is the file format of the users being imported or exported.
+type Format string
+
+const (
+	// JSONL formats each user as a JSON object in its own line.
+	JSONL Format = "jsonl"
+	// CSV formats each user as a comma separated values row, with a header row first.
+	CSV Format = "csv"
+)
+
+// Column maps a column of the file to a field of the user.
+type Column struct {
+	// Name is the name of the column in the file: the CSV header, or the key of the JSON object.
+	Name string
+	// Field is the JSON name of the User field, like "first_name".
+	Field string
+}
+
+// field accesses a User field.
+type field struct {
+	get func(*restuser.User) string
+	set func(*restuser.User, string)
+}
+
+// fieldNames lists the User fields in the same order as they're defined.
+var fieldNames = []string{
+	"id",
+	"created_at",
+	"updated_at",
+	"first_name",
+	"last_name",
+	"name",
+	"email",
+	"password",
+	"password_hash",
+	"password_salt",
+	"country",
+}
+
+var fields = map[string]field{
+	"id": {
+		get: func(u *restuser.User) string { return u.ID },
+		set: func(u *restuser.User, v string) { u.ID = v },
+	},
+	"created_at": {
+		get: func(u *restuser.User) string { return u.CreatedAt },
+		set: func(u *restuser.User, v string) { u.CreatedAt = v },
+	},
+	"updated_at": {
+		get: func(u *restuser.User) string { return u.UpdatedAt },
+		set: func(u *restuser.User, v string) { u.UpdatedAt = v },
+	},
+	"first_name": {
+		get: func(u *restuser.User) string { return u.FirstName },
+		set: func(u *restuser.User, v string) { u.FirstName = v },
+	},
+	"last_name": {
+		get: func(u *restuser.User) string { return u.LastName },
+		set: func(u *restuser.User, v string) { u.LastName = v },
+	},
+	"name": {
+		get: func(u *restuser.User) string { return u.Name },
+		set: func(u *restuser.User, v string) { u.Name = v },
+	},
+	"email": {
+		get: func(u *restuser.User) string { return u.Email },
+		set: func(u *restuser.User, v string) { u.Email = v },
+	},
+	"password": {
+		get: func(u *restuser.User) string { return u.Password },
+		set: func(u *restuser.User, v string) { u.Password = v },
+	},
+	"password_hash": {
+		get: func(u *restuser.User) string { return u.PasswordHash },
+		set: func(u *restuser.User, v string) { u.PasswordHash = v },
+	},
+	"password_salt": {
+		get: func(u *restuser.User) string { return u.PasswordSalt },
+		set: func(u *restuser.User, v string) { u.PasswordSalt = v },
+	},
+	"country": {
+		get: func(u *restuser.User) string { return u.Country },
+		set: func(u *restuser.User, v string) { u.Country = v },
+	},
+}
+
+// DefaultExportColumns returns the columns exported by default: all the User fields returned by the user listing,
+// named as their JSON names.
+func DefaultExportColumns() []Column {
+	var columns []Column
+	for _, name := range fieldNames {
+		switch name {
+		case "password", "password_hash", "password_salt":
+			// never returned when listing users
+		default:
+			columns = append(columns, Column{Name: name, Field: name})
+		}
+	}
+	return columns
+}
+
+// ParseColumns parses a comma separated list of columns, where each one is either a field name,
+// or a column name and a field name separated by "=", like "id,nickname=name,country".
+func ParseColumns(s string) ([]Column, error) {
+	var columns []Column
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		column := Column{Name: part, Field: part}
+		if i := strings.Index(part, "="); i >= 0 {
+			column = Column{Name: part[:i], Field: part[i+1:]}
+		}
+		columns = append(columns, column)
+	}
+	return columns, validateColumns(columns)
+}
+
+func validateColumns(columns []Column) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns provided")
+	}
+	names := map[string]bool{}
+	for _, column := range columns {
+		if _, ok := fields[column.Field]; !ok {
+			return fmt.Errorf("unknown user field %q", column.Field)
+		}
+		if column.Name == "" {
+			return fmt.Errorf("column for field %q has no name", column.Field)
+		}
+		if names[column.Name] {
+			return fmt.Errorf("duplicated column %q", column.Name)
+		}
+		names[column.Name] = true
+	}
+	return nil
+}
+
+// columnFields returns the fields indexed by column name.
+// If no columns are provided, all the fields are indexed by their JSON names.
+func columnFields(columns []Column) (map[string]field, error) {
+	if columns == nil {
+		return fields, nil
+	}
+	if err := validateColumns(columns); err != nil {
+		return nil, err
+	}
+	byName := make(map[string]field, len(columns))
+	for _, column := range columns {
+		byName[column.Name] = fields[column.Field]
+	}
+	return byName, nil
+}
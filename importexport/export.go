@@ -0,0 +1,111 @@
+package importexport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// Params filters the users exported. Limit configures the size of the pages requested.
+	Params restuser.ListUsersParams
+	// Columns configures the fields exported and their names, DefaultExportColumns are used if empty.
+	Columns []Column
+}
+
+// Export writes the users listed from the API to w in the given format, one page at a time.
+// It returns the amount of users exported.
+func Export(ctx context.Context, api *restuser.API, w io.Writer, format Format, opts ExportOptions) (int, error) {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultExportColumns()
+	}
+	if err := validateColumns(columns); err != nil {
+		return 0, err
+	}
+
+	var enc encoder
+	switch format {
+	case JSONL:
+		enc = &jsonlEncoder{enc: json.NewEncoder(w), columns: columns}
+	case CSV:
+		enc = &csvEncoder{w: csv.NewWriter(w), columns: columns}
+	default:
+		return 0, fmt.Errorf("unknown format %q", format)
+	}
+
+	if err := enc.begin(); err != nil {
+		return 0, fmt.Errorf("can't write header: %w", err)
+	}
+	var count int
+	it := api.IterateUsers(opts.Params)
+	for it.Next(ctx) {
+		user := it.User()
+		if err := enc.encode(&user); err != nil {
+			return count, fmt.Errorf("can't write user %s: %w", user.ID, err)
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		return count, fmt.Errorf("can't list users: %w", err)
+	}
+	if err := enc.end(); err != nil {
+		return count, fmt.Errorf("can't flush output: %w", err)
+	}
+	return count, nil
+}
+
+type encoder interface {
+	begin() error
+	encode(*restuser.User) error
+	end() error
+}
+
+type jsonlEncoder struct {
+	enc     *json.Encoder
+	columns []Column
+}
+
+func (e *jsonlEncoder) begin() error { return nil }
+
+func (e *jsonlEncoder) encode(user *restuser.User) error {
+	// a struct would keep the order of the columns, but they're dynamic
+	row := make(map[string]string, len(e.columns))
+	for _, column := range e.columns {
+		row[column.Name] = fields[column.Field].get(user)
+	}
+	return e.enc.Encode(row)
+}
+
+func (e *jsonlEncoder) end() error { return nil }
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []Column
+}
+
+func (e *csvEncoder) begin() error {
+	header := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		header[i] = column.Name
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) encode(user *restuser.User) error {
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		record[i] = fields[column.Field].get(user)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,353 @@
+package importexport
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+const minPasswordLength = 8
+
+// Creator creates users, it is implemented by restuser.API and any restuser.UserService.
+type Creator interface {
+	CreateUser(ctx context.Context, user *restuser.User) (*restuser.User, error)
+}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// Columns maps the names of the columns in the input to the user fields.
+	// If empty, the columns are expected to be named as the JSON names of the fields.
+	Columns []Column
+	// Concurrency is the maximum amount of users being created at the same time, defaults to 1.
+	Concurrency int
+	// Skip is the amount of rows to skip, to resume an import from a checkpoint.
+	Skip int
+	// Checkpoint is called, if provided, each time more rows are processed, with the amount of rows processed
+	// since the beginning of the input, including the skipped ones. It can be provided as Skip to resume the import.
+	// If it returns an error, the import is stopped.
+	// Rows that couldn't be created for a transient reason, like the context being done or the service responding
+	// 429 or 5xx, stop the import and are never checkpointed, so they're imported again when resuming.
+	// With a Concurrency above 1, the rows after them that were already being created are imported again too.
+	Checkpoint func(rows int) error
+	// DryRun only validates the rows, without creating the users.
+	DryRun bool
+}
+
+// RowError is an error importing a specific row of the input.
+type RowError struct {
+	// Row is the 1-based position of the user in the input, not counting the CSV header nor empty JSONL lines.
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Report summarizes the result of an import.
+type Report struct {
+	// Processed is the amount of rows processed, not including the skipped ones.
+	Processed int
+	// Created is the amount of users created, or that would be created on a dry run.
+	Created int
+	// Errors are the errors of the rows that couldn't be imported, sorted by row.
+	Errors []RowError
+}
+
+// Import reads the users from r in the given format and creates them.
+// The ID, CreatedAt, UpdatedAt, PasswordHash and PasswordSalt fields are ignored, since they're set by the service,
+// and the users should have a Password, since it can't be exported.
+// Errors on specific rows don't stop the import, they're provided in the report,
+// unless they're transient, like the service responding 429 or 5xx, since the row should be imported again.
+// The error returned is the one that stopped the import, if any, and the report is provided anyway.
+func Import(ctx context.Context, svc Creator, r io.Reader, format Format, opts ImportOptions) (*Report, error) {
+	fieldsByName, err := columnFields(opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows rowReader
+	switch format {
+	case JSONL:
+		rows = &jsonlReader{r: bufio.NewReader(r), fields: fieldsByName}
+	case CSV:
+		rows = &csvReader{r: csv.NewReader(r), fields: fieldsByName}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	t := &tracker{report: &Report{}, processed: opts.Skip, done: map[int]bool{}, checkpoint: opts.Checkpoint}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	var stopErr error
+loop:
+	for row := 1; ; row++ {
+		user, err := rows.next()
+		var invalid invalidRowError
+		switch {
+		case errors.Is(err, io.EOF):
+			break loop
+		case errors.As(err, &invalid):
+			err = invalid.err
+		case err != nil:
+			stopErr = fmt.Errorf("can't read row %d: %w", row, err)
+			break loop
+		}
+		if row <= opts.Skip {
+			continue
+		}
+		if stopErr = t.failed(); stopErr != nil {
+			break
+		}
+		if err == nil {
+			err = validate(user)
+		}
+		if err != nil || opts.DryRun {
+			t.finish(row, err)
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			stopErr = ctx.Err()
+			break loop
+		}
+		// a previous row could have failed to checkpoint while waiting
+		if stopErr = t.failed(); stopErr != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(row int, user *restuser.User) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, err := svc.CreateUser(ctx, user)
+			if err != nil && !isPermanent(err) {
+				t.interrupt(row, err)
+				return
+			}
+			t.finish(row, err)
+		}(row, user)
+	}
+	wg.Wait()
+
+	if stopErr == nil {
+		stopErr = t.failed()
+	}
+	sort.Slice(t.report.Errors, func(i, j int) bool { return t.report.Errors[i].Row < t.report.Errors[j].Row })
+	return t.report, stopErr
+}
+
+// validate checks the user to be created, clearing the fields set by the service.
+func validate(user *restuser.User) error {
+	user.ID = ""
+	user.CreatedAt = ""
+	user.UpdatedAt = ""
+	user.PasswordHash = ""
+	user.PasswordSalt = ""
+	if len(user.Password) < minPasswordLength {
+		return fmt.Errorf("password should be at least %d characters long", minPasswordLength)
+	}
+	return nil
+}
+
+// tracker collects the results of the rows and calls the checkpoint when all the rows up to one are finished.
+type tracker struct {
+	mu         sync.Mutex
+	report     *Report
+	processed  int
+	done       map[int]bool
+	checkpoint func(int) error
+	// checkpointFailed stops calling the checkpoint, while err stops the import for any reason.
+	checkpointFailed bool
+	err              error
+}
+
+func (t *tracker) finish(row int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(row, err)
+	t.done[row] = true
+	advanced := false
+	for t.done[t.processed+1] {
+		delete(t.done, t.processed+1)
+		t.processed++
+		advanced = true
+	}
+	if advanced && t.checkpoint != nil && !t.checkpointFailed {
+		if err := t.checkpoint(t.processed); err != nil {
+			t.checkpointFailed = true
+			if t.err == nil {
+				t.err = fmt.Errorf("can't checkpoint row %d: %w", t.processed, err)
+			}
+		}
+	}
+}
+
+// interrupt collects the result of a row that should be imported again when resuming,
+// so it's not marked as done and the checkpoint doesn't move past it, and stops the import.
+func (t *tracker) interrupt(row int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(row, err)
+	if t.err == nil {
+		t.err = fmt.Errorf("can't import row %d: %w", row, err)
+	}
+}
+
+func (t *tracker) record(row int, err error) {
+	t.report.Processed++
+	if err != nil {
+		t.report.Errors = append(t.report.Errors, RowError{Row: row, Err: err})
+	} else {
+		t.report.Created++
+	}
+}
+
+func (t *tracker) failed() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// isPermanent reports whether err, returned when creating a user, is caused by the service rejecting it,
+// so creating it again would fail too. Network errors, timeouts, 429 and 5xx responses are not permanent.
+func isPermanent(err error) bool {
+	var apiErr restuser.Error
+	var unexpected restuser.UnexpectedStatusError
+	switch {
+	case errors.As(err, &apiErr):
+		return isPermanentStatus(apiErr.StatusCode)
+	case errors.As(err, &unexpected):
+		return isPermanentStatus(unexpected.StatusCode)
+	default:
+		return false
+	}
+}
+
+func isPermanentStatus(statusCode int) bool {
+	return statusCode < http.StatusInternalServerError && statusCode != http.StatusTooManyRequests
+}
+
+// rowReader reads the users from the input.
+// It returns an invalidRowError if the row can't be parsed, and io.EOF when there are no more rows.
+type rowReader interface {
+	next() (*restuser.User, error)
+}
+
+// invalidRowError is returned by rowReader when a row can't be parsed, it doesn't stop the import.
+type invalidRowError struct {
+	err error
+}
+
+func (e invalidRowError) Error() string {
+	return e.err.Error()
+}
+
+type jsonlReader struct {
+	r      *bufio.Reader
+	fields map[string]field
+}
+
+func (j *jsonlReader) next() (*restuser.User, error) {
+	for {
+		line, err := j.r.ReadBytes('\n')
+		if len(line) == 0 || isBlank(line) {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		var values map[string]interface{}
+		if err := json.Unmarshal(line, &values); err != nil {
+			return nil, invalidRowError{fmt.Errorf("invalid JSON: %w", err)}
+		}
+		var user restuser.User
+		for name, value := range values {
+			f, ok := j.fields[name]
+			if !ok {
+				return nil, invalidRowError{fmt.Errorf("unknown column %q", name)}
+			}
+			s, ok := value.(string)
+			if !ok && value != nil {
+				return nil, invalidRowError{fmt.Errorf("column %q should be a string", name)}
+			}
+			f.set(&user, s)
+		}
+		return &user, nil
+	}
+}
+
+func isBlank(line []byte) bool {
+	for _, b := range line {
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	fields map[string]field
+	header []field
+}
+
+func (c *csvReader) next() (*restuser.User, error) {
+	if c.header == nil {
+		c.r.FieldsPerRecord = -1
+		names, err := c.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			f, ok := c.fields[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown column %q in header", name)
+			}
+			c.header = append(c.header, f)
+		}
+	}
+
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, invalidRowError{err}
+		}
+		return nil, err
+	}
+	if len(record) != len(c.header) {
+		return nil, invalidRowError{fmt.Errorf("expected %d columns, got %d", len(c.header), len(record))}
+	}
+	var user restuser.User
+	for i, value := range record {
+		c.header[i].set(&user, value)
+	}
+	return &user, nil
+}
@@ -0,0 +1,258 @@
+package importexport_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/importexport"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var users []*restuser.User
+	for _, name := range []string{"pepe", "pierre", "paco"} {
+		country := "es"
+		if name == "pierre" {
+			country = "fr"
+		}
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: name, Password: "password123", Country: country})
+		require.NoError(t, err)
+		users = append(users, user)
+	}
+
+	t.Run("csv with columns", func(t *testing.T) {
+		columns, err := importexport.ParseColumns("nickname=name,country")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		count, err := importexport.Export(ctx, srv.API(), &buf, importexport.CSV, importexport.ExportOptions{
+			Params:  restuser.ListUsersParams{Country: "es", Limit: 1},
+			Columns: columns,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, "nickname,country", lines[0])
+		assert.ElementsMatch(t, []string{"pepe,es", "paco,es"}, lines[1:])
+	})
+
+	t.Run("jsonl with default columns", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := importexport.Export(ctx, srv.API(), &buf, importexport.JSONL, importexport.ExportOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		for _, line := range lines {
+			assert.Contains(t, line, `"id":"`)
+			assert.Contains(t, line, `"created_at":"`)
+			assert.NotContains(t, line, "password")
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := importexport.Export(ctx, srv.API(), &bytes.Buffer{}, importexport.CSV, importexport.ExportOptions{
+			Columns: []importexport.Column{{Name: "foo", Field: "foo"}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+
+	const someCSV = `nickname,password,country,id
+pepe,password123,es,ignored
+pierre,short,fr,
+paco,password123,es
+maria,password123,pt,
+`
+	columns := []importexport.Column{
+		{Name: "nickname", Field: "name"},
+		{Name: "password", Field: "password"},
+		{Name: "country", Field: "country"},
+		{Name: "id", Field: "id"},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		srv := restusertest.NewServer()
+		defer srv.Close()
+
+		var checkpoints []int
+		report, err := importexport.Import(ctx, srv.API(), strings.NewReader(someCSV), importexport.CSV, importexport.ImportOptions{
+			Columns:     columns,
+			Concurrency: 2,
+			Checkpoint: func(rows int) error {
+				checkpoints = append(checkpoints, rows)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Processed)
+		assert.Equal(t, 2, report.Created)
+		require.Len(t, report.Errors, 2)
+		assert.Equal(t, 2, report.Errors[0].Row)
+		assert.EqualError(t, report.Errors[0], "row 2: password should be at least 8 characters long")
+		assert.Equal(t, 3, report.Errors[1].Row)
+		assert.Equal(t, 4, checkpoints[len(checkpoints)-1])
+
+		users, err := srv.API().ListUsers(ctx, restuser.ListUsersParams{})
+		require.NoError(t, err)
+		var names []string
+		for _, user := range users {
+			names = append(names, user.Name)
+		}
+		assert.ElementsMatch(t, []string{"pepe", "maria"}, names)
+	})
+
+	t.Run("jsonl resuming from checkpoint", func(t *testing.T) {
+		const someJSONL = `{"name":"pepe","password":"password123"}
+
+{"name":"pierre","password":"password123"}
+{"name":"paco","password":"password123","unknown":"foo"}
+{"name":"maria","password":1}
+`
+		creator := &recordingCreator{}
+		report, err := importexport.Import(ctx, creator, strings.NewReader(someJSONL), importexport.JSONL, importexport.ImportOptions{
+			Skip: 1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Processed)
+		assert.Equal(t, 1, report.Created)
+		require.Len(t, report.Errors, 2)
+		assert.Equal(t, 3, report.Errors[0].Row)
+		assert.Equal(t, 4, report.Errors[1].Row)
+		assert.Equal(t, []string{"pierre"}, creator.names)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		creator := &recordingCreator{}
+		report, err := importexport.Import(ctx, creator, strings.NewReader(someCSV), importexport.CSV, importexport.ImportOptions{
+			Columns: columns,
+			DryRun:  true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.Len(t, report.Errors, 2)
+		assert.Empty(t, creator.names)
+	})
+
+	t.Run("checkpoint failure stops import", func(t *testing.T) {
+		creator := &recordingCreator{}
+		someErr := errors.New("disk full")
+		_, err := importexport.Import(ctx, creator, strings.NewReader(someCSV), importexport.CSV, importexport.ImportOptions{
+			Columns:    columns,
+			Checkpoint: func(int) error { return someErr },
+		})
+		assert.True(t, errors.Is(err, someErr))
+		assert.Len(t, creator.names, 1)
+	})
+
+	t.Run("resuming after cancellation", func(t *testing.T) {
+		const someJSONL = `{"name":"pepe","password":"password123"}
+{"name":"pierre","password":"password123"}
+{"name":"paco","password":"password123"}
+{"name":"maria","password":"password123"}
+`
+		creator := &recordingCreator{}
+		cancelCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		checkpoint := 0
+		opts := importexport.ImportOptions{
+			Checkpoint: func(rows int) error {
+				checkpoint = rows
+				return nil
+			},
+		}
+		cancelling := creatorFunc(func(ctx context.Context, user *restuser.User) (*restuser.User, error) {
+			if user.Name == "paco" {
+				cancel()
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return creator.CreateUser(ctx, user)
+		})
+		_, err := importexport.Import(cancelCtx, cancelling, strings.NewReader(someJSONL), importexport.JSONL, opts)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, 2, checkpoint)
+
+		opts.Skip = checkpoint
+		report, err := importexport.Import(ctx, creator, strings.NewReader(someJSONL), importexport.JSONL, opts)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 4, checkpoint)
+		assert.Equal(t, []string{"pepe", "pierre", "paco", "maria"}, creator.names)
+	})
+
+	t.Run("resuming after a transient failure", func(t *testing.T) {
+		const someJSONL = `{"name":"pepe","password":"password123"}
+{"name":"pierre","password":"password123"}
+{"name":"paco","password":"password123"}
+{"name":"maria","password":"password123"}
+`
+		creator := &recordingCreator{}
+		checkpoint := 0
+		opts := importexport.ImportOptions{
+			Checkpoint: func(rows int) error {
+				checkpoint = rows
+				return nil
+			},
+		}
+		unavailable := creatorFunc(func(ctx context.Context, user *restuser.User) (*restuser.User, error) {
+			if user.Name == "pierre" {
+				return nil, restuser.RetryAfterError{StatusCode: http.StatusServiceUnavailable}
+			}
+			return creator.CreateUser(ctx, user)
+		})
+		report, err := importexport.Import(ctx, unavailable, strings.NewReader(someJSONL), importexport.JSONL, opts)
+		assert.True(t, restuser.IsServiceUnavailable(err))
+		assert.Equal(t, 1, checkpoint)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, []string{"pepe"}, creator.names)
+
+		opts.Skip = checkpoint
+		report, err = importexport.Import(ctx, creator, strings.NewReader(someJSONL), importexport.JSONL, opts)
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Created)
+		assert.Equal(t, 4, checkpoint)
+		assert.Equal(t, []string{"pepe", "pierre", "paco", "maria"}, creator.names)
+	})
+
+	t.Run("unknown header", func(t *testing.T) {
+		_, err := importexport.Import(ctx, &recordingCreator{}, strings.NewReader(someCSV), importexport.CSV, importexport.ImportOptions{})
+		assert.EqualError(t, err, `can't read row 1: unknown column "nickname" in header`)
+	})
+}
+
+type recordingCreator struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (c *recordingCreator) CreateUser(_ context.Context, user *restuser.User) (*restuser.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names = append(c.names, user.Name)
+	return user, nil
+}
+
+type creatorFunc func(ctx context.Context, user *restuser.User) (*restuser.User, error)
+
+func (f creatorFunc) CreateUser(ctx context.Context, user *restuser.User) (*restuser.User, error) {
+	return f(ctx, user)
+}
@@ -0,0 +1,179 @@
+package restuser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UserLoaderConfig configures the UserLoader.
+// Zero values are replaced by the ones from DefaultUserLoaderConfig when provided to NewUserLoader.
+type UserLoaderConfig struct {
+	// Wait is the time the IDs requested are collected before dispatching them in a single batch.
+	Wait time.Duration
+	// MaxBatch is the maximum amount of IDs requested in a single batch, it's dispatched right away once reached.
+	MaxBatch int
+	// Timeout is the maximum time a batch takes, so the lookups waiting for it don't hang if the service does.
+	Timeout time.Duration
+}
+
+// DefaultUserLoaderConfig returns the UserLoaderConfig used to complete the zero values of the provided one.
+func DefaultUserLoaderConfig() UserLoaderConfig {
+	return UserLoaderConfig{
+		Wait:     2 * time.Millisecond,
+		MaxBatch: 100,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// UserLoader coalesces the concurrent lookups of users by ID:
+// lookups of the same ID share a single result, and the IDs requested within a short window
+// are retrieved with a single ListUsers call filtered by IDs.
+// Users are retrieved through ListUsers, so the PasswordHash and PasswordSalt fields are not provided.
+// If the service is an API, all the pages of each batch are retrieved, otherwise it should list them in a single page.
+type UserLoader struct {
+	svc UserService
+	cfg UserLoaderConfig
+
+	mu sync.Mutex
+	// calls are the lookups pending or in flight, by ID
+	calls map[string]*loaderCall
+	// batch is the batch collecting IDs, nil if there's none
+	batch *loaderBatch
+}
+
+type loaderBatch struct {
+	ids   []string
+	calls []*loaderCall
+	timer *time.Timer
+}
+
+type loaderCall struct {
+	id   string
+	done chan struct{}
+	user *User
+	err  error
+}
+
+// NewUserLoader creates a UserLoader retrieving the users from the service, which is usually an API.
+func NewUserLoader(svc UserService, cfg UserLoaderConfig) *UserLoader {
+	defaults := DefaultUserLoaderConfig()
+	if cfg.Wait == 0 {
+		cfg.Wait = defaults.Wait
+	}
+	if cfg.MaxBatch == 0 {
+		cfg.MaxBatch = defaults.MaxBatch
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	return &UserLoader{svc: svc, cfg: cfg, calls: map[string]*loaderCall{}}
+}
+
+// Load retrieves the user with the given ID, returning an error matching ErrNotFound if it doesn't exist.
+// The batches are performed with a background context limited by the configured Timeout,
+// since they're shared by several callers, ctx only stops waiting for the result.
+func (l *UserLoader) Load(ctx context.Context, id string) (*User, error) {
+	l.mu.Lock()
+	call, ok := l.calls[id]
+	if !ok {
+		call = &loaderCall{id: id, done: make(chan struct{})}
+		l.calls[id] = call
+		l.enqueue(call)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		user := *call.user
+		return &user, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// LoadMany retrieves the users with the given IDs, returning the user or error for each one of them in the same order.
+func (l *UserLoader) LoadMany(ctx context.Context, ids []string) ([]*User, []error) {
+	users := make([]*User, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			users[i], errs[i] = l.Load(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+	return users, errs
+}
+
+// enqueue adds the call to the batch being collected, l.mu should be held.
+func (l *UserLoader) enqueue(call *loaderCall) {
+	if l.batch == nil {
+		batch := &loaderBatch{}
+		batch.timer = time.AfterFunc(l.cfg.Wait, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.batch == batch {
+				l.dispatch()
+			}
+		})
+		l.batch = batch
+	}
+	l.batch.ids = append(l.batch.ids, call.id)
+	l.batch.calls = append(l.batch.calls, call)
+	if len(l.batch.ids) >= l.cfg.MaxBatch {
+		l.batch.timer.Stop()
+		l.dispatch()
+	}
+}
+
+// dispatch retrieves the users of the current batch in the background, l.mu should be held.
+func (l *UserLoader) dispatch() {
+	batch := l.batch
+	l.batch = nil
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), l.cfg.Timeout)
+		defer cancel()
+		users, err := l.list(ctx, batch.ids)
+		byID := make(map[string]*User, len(users))
+		for i := range users {
+			byID[users[i].ID] = &users[i]
+		}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, call := range batch.calls {
+			switch user, ok := byID[call.id]; {
+			case err != nil:
+				call.err = err
+			case !ok:
+				call.err = fmt.Errorf("user %s: %w", call.id, ErrNotFound)
+			default:
+				call.user = user
+			}
+			delete(l.calls, call.id)
+			close(call.done)
+		}
+	}()
+}
+
+// list retrieves the users with the given IDs, following the pages if the service is an API.
+func (l *UserLoader) list(ctx context.Context, ids []string) ([]User, error) {
+	params := ListUsersParams{IDs: ids}
+	api, ok := l.svc.(*API)
+	if !ok {
+		return l.svc.ListUsers(ctx, params)
+	}
+	var users []User
+	it := api.IterateUsers(params)
+	for it.Next(ctx) {
+		users = append(users, it.User())
+	}
+	return users, it.Err()
+}
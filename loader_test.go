@@ -0,0 +1,163 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/a-faceit-candidate/restuser/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserLoader(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var ids []string
+	for _, name := range []string{"first", "second", "third"} {
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: name, Password: "password123"})
+		require.NoError(t, err)
+		ids = append(ids, user.ID)
+	}
+	const missing = "c3e11b46-109c-11eb-adc1-0242ac120002"
+
+	// batches records the IDs requested by each ListUsers call
+	var mu sync.Mutex
+	var batches [][]string
+	recorder := func(next restuser.UserService) restuser.UserService {
+		return listRecorder{UserService: next, record: func(params restuser.ListUsersParams) {
+			mu.Lock()
+			defer mu.Unlock()
+			sorted := append([]string(nil), params.IDs...)
+			sort.Strings(sorted)
+			batches = append(batches, sorted)
+		}}
+	}
+
+	t.Run("coalesces concurrent lookups", func(t *testing.T) {
+		batches = nil
+		loader := restuser.NewUserLoader(restuser.Decorate(srv.API(), recorder), restuser.UserLoaderConfig{Wait: 20 * time.Millisecond})
+
+		lookups := []string{ids[0], ids[1], ids[0], missing, ids[2], ids[1]}
+		users, errs := loader.LoadMany(ctx, lookups)
+		for i, id := range lookups {
+			if id == missing {
+				assert.True(t, restuser.IsNotFound(errs[i]))
+				continue
+			}
+			require.NoError(t, errs[i])
+			assert.Equal(t, id, users[i].ID)
+		}
+
+		expected := append([]string{missing}, ids...)
+		sort.Strings(expected)
+		assert.Equal(t, [][]string{expected}, batches)
+	})
+
+	t.Run("dispatches when max batch is reached", func(t *testing.T) {
+		batches = nil
+		loader := restuser.NewUserLoader(restuser.Decorate(srv.API(), recorder), restuser.UserLoaderConfig{Wait: time.Hour, MaxBatch: 3})
+
+		users, errs := loader.LoadMany(ctx, ids)
+		for i := range ids {
+			require.NoError(t, errs[i])
+			assert.Equal(t, ids[i], users[i].ID)
+		}
+		assert.Len(t, batches, 1)
+	})
+
+	t.Run("stops waiting when context is done", func(t *testing.T) {
+		loader := restuser.NewUserLoader(srv.API(), restuser.UserLoaderConfig{Wait: time.Hour})
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := loader.Load(ctx, ids[0])
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+func TestUserLoader_Pages(t *testing.T) {
+	ctx := context.Background()
+	// the service responds a single user in each page, unless a limit is requested
+	handler := server.NewHandler(server.NewMemoryStore())
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet && req.URL.Query().Get("limit") == "" {
+			query := req.URL.Query()
+			query.Set("limit", "1")
+			req.URL.RawQuery = query.Encode()
+		}
+		handler.ServeHTTP(rw, req)
+	}))
+	defer srv.Close()
+	api := restuser.New(restuser.Config{URL: srv.URL})
+
+	var ids []string
+	for _, name := range []string{"first", "second", "third"} {
+		user, err := api.CreateUser(ctx, &restuser.User{Name: name, Password: "password123"})
+		require.NoError(t, err)
+		ids = append(ids, user.ID)
+	}
+
+	loader := restuser.NewUserLoader(api, restuser.UserLoaderConfig{Wait: 20 * time.Millisecond})
+	users, errs := loader.LoadMany(ctx, ids)
+	for i := range ids {
+		require.NoError(t, errs[i])
+		assert.Equal(t, ids[i], users[i].ID)
+	}
+}
+
+func TestUserLoader_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+
+	loader := restuser.NewUserLoader(restuser.New(restuser.Config{URL: srv.URL}), restuser.UserLoaderConfig{Timeout: 20 * time.Millisecond})
+	for i := 0; i < 2; i++ {
+		// the second lookup isn't joined to the batch that timed out
+		_, err := loader.Load(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestListUsers_IDs(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+		ids = append(ids, user.ID)
+	}
+
+	var listed []string
+	it := srv.API().IterateUsers(restuser.ListUsersParams{IDs: ids[1:], Limit: 1})
+	for it.Next(ctx) {
+		listed = append(listed, it.User().ID)
+	}
+	require.NoError(t, it.Err())
+
+	expected := append([]string(nil), ids[1:]...)
+	sort.Strings(expected)
+	assert.Equal(t, expected, listed)
+}
+
+type listRecorder struct {
+	restuser.UserService
+	record func(restuser.ListUsersParams)
+}
+
+func (r listRecorder) ListUsers(ctx context.Context, params restuser.ListUsersParams) ([]restuser.User, error) {
+	r.record(params)
+	return r.UserService.ListUsers(ctx, params)
+}
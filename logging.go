@@ -0,0 +1,213 @@
+package restuser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redacted replaces the values of the sensitive fields in the logged bodies.
+const redacted = "[REDACTED]"
+
+// redactedFields are the JSON fields of the payloads that are never logged.
+var redactedFields = map[string]bool{
+	"password":      true,
+	"password_hash": true,
+	"password_salt": true,
+	"email":         true,
+}
+
+// redactedQueryParams are the query parameters of the URLs that are never logged, since they can contain emails.
+var redactedQueryParams = []string{"email", "q"}
+
+// LogRecord describes a request performed by the API and its response.
+type LogRecord struct {
+	// Operation is the swagger operation ID, like "get-user".
+	Operation string
+	Method    string
+	// URL is the URL requested, with the email and free-text query filters redacted.
+	URL string
+	// Attempt is the number of the attempt, starting at 1, greater when the request is retried.
+	Attempt  int
+	Duration time.Duration
+	// StatusCode is the status code of the response, or 0 if it wasn't received.
+	StatusCode int
+	// Err is the error performing the request, if any.
+	Err error
+	// RequestBody and ResponseBody are only set when the logger was configured with WithDebugLogger.
+	// The password, password hash, password salt and email fields are always redacted.
+	RequestBody  string
+	ResponseBody string
+}
+
+// Logger receives a record for each request performed by the API.
+type Logger interface {
+	Log(ctx context.Context, record LogRecord)
+}
+
+// LoggerFunc is an adapter to use a function as a Logger.
+type LoggerFunc func(ctx context.Context, record LogRecord)
+
+// Log implements Logger.
+func (f LoggerFunc) Log(ctx context.Context, record LogRecord) {
+	f(ctx, record)
+}
+
+// PrintfLogger adapts a printf-like function, like log.Printf, to a Logger that formats the records as key=value pairs.
+func PrintfLogger(logf func(format string, args ...interface{})) Logger {
+	return LoggerFunc(func(_ context.Context, r LogRecord) {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "restuser: operation=%s method=%s url=%q attempt=%d duration=%s status=%d",
+			r.Operation, r.Method, r.URL, r.Attempt, r.Duration, r.StatusCode)
+		if r.Err != nil {
+			fmt.Fprintf(&sb, " error=%q", r.Err.Error())
+		}
+		if r.RequestBody != "" {
+			fmt.Fprintf(&sb, " request_body=%q", r.RequestBody)
+		}
+		if r.ResponseBody != "" {
+			fmt.Fprintf(&sb, " response_body=%q", r.ResponseBody)
+		}
+		logf("%s", sb.String())
+	})
+}
+
+// WithLogger configures the API to log each request performed, including retries.
+func WithLogger(logger Logger) Option {
+	return func(api *API) {
+		api.logger = logger
+		api.logBodies = false
+	}
+}
+
+// WithDebugLogger configures the API to log each request performed, including the request and response bodies.
+// Sensitive fields are redacted from the bodies.
+// Response bodies are fully read into memory to be logged, so it shouldn't be used with big listings.
+func WithDebugLogger(logger Logger) Option {
+	return func(api *API) {
+		api.logger = logger
+		api.logBodies = true
+	}
+}
+
+func (a *API) logAttempt(ctx context.Context, op Operation, req *http.Request, attempt int, duration time.Duration, resp *http.Response, err error) {
+	record := LogRecord{
+		Operation: op.ID,
+		Method:    req.Method,
+		URL:       redactURL(req.URL),
+		Attempt:   attempt,
+		Duration:  duration,
+		Err:       redactURLError(err),
+	}
+	if resp != nil {
+		record.StatusCode = resp.StatusCode
+	}
+	if a.logBodies {
+		record.RequestBody = requestBody(req)
+		if resp != nil {
+			record.ResponseBody = responseBody(resp)
+		}
+	}
+	a.logger.Log(ctx, record)
+}
+
+// redactURL returns the URL with the values of the redactedQueryParams replaced.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for _, param := range redactedQueryParams {
+		if values, ok := query[param]; ok {
+			for i := range values {
+				values[i] = redacted
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.String()
+}
+
+// redactURLError redacts the URL of the errors performing the requests, which contain it.
+func redactURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		if parsed, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			return &url.Error{Op: urlErr.Op, URL: redactURL(parsed), Err: urlErr.Err}
+		}
+	}
+	return err
+}
+
+// requestBody returns the redacted body of the request, without consuming it.
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return redactJSON(data)
+}
+
+// responseBody reads the body of the response, replacing it so it can be read again, and returns it redacted.
+func responseBody(resp *http.Response) string {
+	data, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Sprintf("can't read body: %s", err)
+	}
+	return redactJSON(data)
+}
+
+// redactJSON replaces the values of the redactedFields found at any level of the JSON document.
+// If data is not valid JSON, it's not logged at all, since the sensitive fields can't be found.
+func redactJSON(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Sprintf("[%d bytes of invalid JSON]", len(data))
+	}
+	redacted, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return fmt.Sprintf("can't marshal redacted JSON: %s", err)
+	}
+	return string(redacted)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redactedFields[key] {
+				if s, ok := value.(string); !ok || s != "" {
+					v[key] = redacted
+				}
+				continue
+			}
+			v[key] = redactValue(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package restuser_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var records []restuser.LogRecord
+	api := restuser.New(restuser.Config{URL: srv.URL},
+		restuser.WithLogger(restuser.LoggerFunc(func(_ context.Context, record restuser.LogRecord) {
+			records = append(records, record)
+		})),
+		restuser.WithRetryPolicy(restuser.RetryPolicy{InitialBackoff: time.Millisecond}),
+	)
+	require.NoError(t, api.DeleteUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002"))
+
+	require.Len(t, records, 2)
+	for i, record := range records {
+		assert.Equal(t, "delete-user", record.Operation)
+		assert.Equal(t, http.MethodDelete, record.Method)
+		assert.Equal(t, srv.URL+"/v1/users/c3e11b46-109c-11eb-adc1-0242ac120002", record.URL)
+		assert.Equal(t, i+1, record.Attempt)
+		assert.True(t, record.Duration > 0)
+		assert.NoError(t, record.Err)
+		assert.Empty(t, record.RequestBody)
+		assert.Empty(t, record.ResponseBody)
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, records[0].StatusCode)
+	assert.Equal(t, http.StatusNoContent, records[1].StatusCode)
+}
+
+func TestWithLogger_RedactsQuery(t *testing.T) {
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var records []restuser.LogRecord
+	logger := restuser.LoggerFunc(func(_ context.Context, record restuser.LogRecord) {
+		records = append(records, record)
+	})
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithLogger(logger))
+	_, err := api.ListUsers(context.Background(), restuser.ListUsersParams{Email: "pepe@faceit.com", Query: "pepe@", Country: "es"})
+	require.NoError(t, err)
+	_, err = api.GetUserByEmail(context.Background(), "pepe@faceit.com")
+	assert.True(t, restuser.IsNotFound(err))
+	assert.NotContains(t, err.Error(), "pepe")
+
+	// the request can't be performed, so the error contains the URL
+	unreachable := restuser.New(restuser.Config{URL: "http://127.0.0.1:0"}, restuser.WithLogger(logger))
+	_, err = unreachable.GetUserByEmail(context.Background(), "pepe@faceit.com")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "pepe")
+
+	require.Len(t, records, 3)
+	assert.Equal(t, srv.URL+"/v1/users?country=es&email=%5BREDACTED%5D&q=%5BREDACTED%5D", records[0].URL)
+	for _, record := range records {
+		assert.NotContains(t, record.URL, "pepe")
+		assert.NotContains(t, fmt.Sprint(record.Err), "pepe")
+	}
+	assert.Error(t, records[2].Err)
+}
+
+func TestWithDebugLogger(t *testing.T) {
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var records []restuser.LogRecord
+	api := restuser.New(restuser.Config{URL: srv.URL},
+		restuser.WithDebugLogger(restuser.LoggerFunc(func(_ context.Context, record restuser.LogRecord) {
+			records = append(records, record)
+		})),
+	)
+
+	ctx := context.Background()
+	user, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Email: "pepe@example.com", Password: "password123"})
+	require.NoError(t, err)
+	// the response body can still be decoded after being logged
+	assert.Equal(t, "pepe@example.com", user.Email)
+	assert.NotEmpty(t, user.PasswordHash)
+
+	users, err := api.ListUsers(ctx, restuser.ListUsersParams{})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	require.Len(t, records, 2)
+	for _, record := range records {
+		for _, secret := range []string{"pepe@example.com", "password123", user.PasswordHash, user.PasswordSalt} {
+			assert.NotContains(t, record.RequestBody, secret)
+			assert.NotContains(t, record.ResponseBody, secret)
+		}
+	}
+	assert.Contains(t, records[0].RequestBody, `"name":"pepe"`)
+	assert.Contains(t, records[0].RequestBody, `"password":"[REDACTED]"`)
+	assert.Contains(t, records[0].ResponseBody, `"email":"[REDACTED]"`)
+	assert.Contains(t, records[0].ResponseBody, `"password_hash":"[REDACTED]"`)
+	assert.Contains(t, records[1].ResponseBody, `"id":"`+user.ID+`"`)
+	assert.Contains(t, records[1].ResponseBody, `"email":"[REDACTED]"`)
+}
+
+func TestPrintfLogger(t *testing.T) {
+	var logs []string
+	logger := restuser.PrintfLogger(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+	logger.Log(context.Background(), restuser.LogRecord{
+		Operation:   "post-user",
+		Method:      http.MethodPost,
+		URL:         "http://localhost/v1/users",
+		Attempt:     1,
+		Duration:    time.Second,
+		StatusCode:  http.StatusCreated,
+		RequestBody: `{"name":"pepe"}`,
+	})
+
+	require.Len(t, logs, 1)
+	assert.Equal(t, `restuser: operation=post-user method=POST url="http://localhost/v1/users" attempt=1 duration=1s status=201 request_body="{\"name\":\"pepe\"}"`, logs[0])
+}
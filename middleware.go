@@ -0,0 +1,35 @@
+package restuser
+
+import (
+	"context"
+	"net/http"
+)
+
+// Invoker performs an operation, returning its response.
+// The context provided is the one used to build the requests.
+type Invoker func(ctx context.Context) (*http.Response, error)
+
+// Middleware wraps the invocation of each operation performed by the API, including all its retries.
+// It is useful to instrument the client, for instance.
+// The response body should not be read by the middleware, since it's read by the API methods afterwards.
+type Middleware func(op Operation, next Invoker) Invoker
+
+// WithMiddleware configures the API to wrap each operation with the given middleware.
+// It can be provided several times, and the first middleware provided is the outermost one.
+func WithMiddleware(mw Middleware) Option {
+	return func(api *API) {
+		api.middlewares = append(api.middlewares, mw)
+	}
+}
+
+// RequestEditor is called with each request built, before being sent.
+// The context provided is the one the request was built with.
+type RequestEditor func(ctx context.Context, req *http.Request) error
+
+// WithRequestEditor configures the API to call the editor with each request built, including the retries.
+// It can be provided several times, and they will be called in the same order.
+func WithRequestEditor(edit RequestEditor) Option {
+	return func(api *API) {
+		api.requestEditors = append(api.requestEditors, edit)
+	}
+}
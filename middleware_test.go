@@ -0,0 +1,93 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var invocations []string
+	recorder := func(name string) restuser.Middleware {
+		return func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+			return func(ctx context.Context) (*http.Response, error) {
+				invocations = append(invocations, name+" before "+op.ID)
+				resp, err := next(ctx)
+				require.NoError(t, err)
+				invocations = append(invocations, name+" after "+resp.Status)
+				return resp, err
+			}
+		}
+	}
+
+	api := restuser.New(restuser.Config{URL: srv.URL},
+		restuser.WithMiddleware(recorder("outer")),
+		restuser.WithMiddleware(recorder("inner")),
+		restuser.WithRetryPolicy(restuser.RetryPolicy{InitialBackoff: time.Millisecond}),
+	)
+	require.NoError(t, api.DeleteUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002"))
+
+	// the retries are performed inside of the middlewares
+	assert.Equal(t, []string{
+		"outer before delete-user",
+		"inner before delete-user",
+		"inner after 204 No Content",
+		"outer after 204 No Content",
+	}, invocations)
+}
+
+func TestWithRequestEditor(t *testing.T) {
+	type ctxKey struct{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "first,second", req.Header.Get("X-Edited"))
+		assert.Equal(t, "from context", req.Header.Get("X-Context"))
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	api := restuser.New(restuser.Config{URL: srv.URL},
+		restuser.WithMiddleware(func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+			return func(ctx context.Context) (*http.Response, error) {
+				return next(context.WithValue(ctx, ctxKey{}, "from context"))
+			}
+		}),
+		restuser.WithRequestEditor(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("X-Edited", "first")
+			req.Header.Set("X-Context", ctx.Value(ctxKey{}).(string))
+			return nil
+		}),
+		restuser.WithRequestEditor(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("X-Edited", req.Header.Get("X-Edited")+",second")
+			return nil
+		}),
+	)
+	require.NoError(t, api.DeleteUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002"))
+
+	t.Run("failing editor", func(t *testing.T) {
+		someErr := errors.New("can't edit")
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRequestEditor(func(context.Context, *http.Request) error {
+			return someErr
+		}))
+		err := api.DeleteUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+		assert.True(t, errors.Is(err, someErr))
+	})
+}
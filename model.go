@@ -1,5 +1,7 @@
 package restuser
 
+import "net/http"
+
 // User describes the main item of the user service: a user.
 type User struct {
 	// ID is generated by the service when the user is created. It is a valid UUID.
@@ -36,7 +38,81 @@ type User struct {
 	Country string `json:"country" example:"es"`
 }
 
+// UserPatch describes a partial update of a user, as a JSON Merge Patch (RFC 7396).
+// Fields that are nil are not modified, and fields pointing to an empty string are cleared.
+// The String function can be used to obtain the pointers.
+type UserPatch struct {
+	FirstName *string `json:"first_name,omitempty" example:"John"`
+	LastName  *string `json:"last_name,omitempty" example:"Doe"`
+	Name      *string `json:"name,omitempty" example:"john_doe87"`
+	Email     *string `json:"email,omitempty" example:"john@colega.eu" format:"email"`
+	// Password can't be cleared, and it should be at least 8 characters long.
+	Password *string `json:"password,omitempty" format:"password"`
+	Country  *string `json:"country,omitempty" example:"es"`
+}
+
+// String returns a pointer to the string, to be used in UserPatch.
+func String(s string) *string {
+	return &s
+}
+
 // ErrorResponse is used to provide further details on non-successful responses.
 type ErrorResponse struct {
 	Message string `json:"message" example:"Something terrible happened."`
 }
+
+// BatchMode decides what happens with the rest of the items of a batch operation when some of them fail.
+type BatchMode string
+
+const (
+	// BestEffort applies the items that succeed, even if other ones fail.
+	BestEffort BatchMode = "best_effort"
+	// AllOrNothing applies none of the items if any of them fails.
+	AllOrNothing BatchMode = "all_or_nothing"
+)
+
+// BatchGetRequest is the body of the batch get operation.
+type BatchGetRequest struct {
+	IDs []string `json:"ids" example:"c3e11b46-109c-11eb-adc1-0242ac120002"`
+}
+
+// BatchCreateRequest is the body of the batch create operation.
+type BatchCreateRequest struct {
+	// Users to be created, with the same restrictions as the users created individually.
+	Users []User `json:"users"`
+	// Mode is `best_effort` if not provided.
+	Mode BatchMode `json:"mode,omitempty" enums:"best_effort,all_or_nothing"`
+}
+
+// BatchDeleteRequest is the body of the batch delete operation.
+type BatchDeleteRequest struct {
+	IDs []string `json:"ids" example:"c3e11b46-109c-11eb-adc1-0242ac120002"`
+	// Mode is `best_effort` if not provided.
+	Mode BatchMode `json:"mode,omitempty" enums:"best_effort,all_or_nothing"`
+}
+
+// BatchResponse is the response of the batch operations.
+type BatchResponse struct {
+	// Results has the result of each item of the request, in the same order.
+	Results []BatchResult `json:"results"`
+}
+
+// BatchResult is the result of a single item of a batch operation.
+type BatchResult struct {
+	// Status is the status code that would be responded if the item was requested individually.
+	// When an `all_or_nothing` batch fails, the items that didn't fail have the 424 Failed Dependency status.
+	Status int `json:"status" example:"200"`
+	// User is provided for the successful items of batch get and batch create operations.
+	// PasswordHash and PasswordSalt are not provided by batch get.
+	User *User `json:"user,omitempty"`
+	// Error is provided for the failed items.
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// Err returns the Error of the item if it failed, or nil.
+func (r BatchResult) Err() error {
+	if r.Error == nil && r.Status < http.StatusBadRequest {
+		return nil
+	}
+	return Error{StatusCode: r.Status, Response: r.Error}
+}
@@ -0,0 +1,58 @@
+package restuser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const defaultModifyAttempts = 3
+
+// ErrModifyAttemptsExhausted is returned by ModifyUser when the user kept being modified concurrently.
+var ErrModifyAttemptsExhausted = errors.New("user was modified concurrently too many times")
+
+// WithModifyAttempts configures the maximum attempts performed by ModifyUser when the update conflicts.
+// Default is 3 attempts, and at least one attempt is always performed.
+func WithModifyAttempts(attempts int) Option {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return func(api *API) {
+		api.modifyAttempts = attempts
+	}
+}
+
+// ModifyUser retrieves the user with the given ID, applies the modification and updates it.
+// If the user was updated concurrently and the service responds 409, the whole process is attempted again,
+// up to the attempts configured by WithModifyAttempts, returning ErrModifyAttemptsExhausted when they are exhausted.
+// The modification can be applied several times, so it should have no other side effects,
+// and if it returns an error, the user is not updated and the error is returned.
+// The ID and UpdatedAt fields are restored after applying the modification.
+func (a *API) ModifyUser(ctx context.Context, id string, modify func(*User) error) (*User, error) {
+	var lastErr error
+	for attempt := 0; attempt < a.modifyAttempts; attempt++ {
+		user, err := a.GetUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		updatedAt := user.UpdatedAt
+		if err := modify(user); err != nil {
+			return nil, err
+		}
+		user.ID = id
+		user.UpdatedAt = updatedAt
+		// these are set by the service, and shouldn't be sent on update requests
+		user.PasswordHash = ""
+		user.PasswordSalt = ""
+
+		updated, err := a.UpdateUser(ctx, user)
+		if err == nil {
+			return updated, nil
+		}
+		if !IsConflict(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w after %d attempts: %s", ErrModifyAttemptsExhausted, a.modifyAttempts, lastErr)
+}
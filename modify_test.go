@@ -0,0 +1,94 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_ModifyUser(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	someUser := func(t *testing.T) *restuser.User {
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123", Country: "es"})
+		require.NoError(t, err)
+		return user
+	}
+
+	// updateConcurrently updates the user through a different client, causing a conflict on the next update.
+	updateConcurrently := func(t *testing.T, id string) {
+		user, err := srv.API().GetUser(ctx, id)
+		require.NoError(t, err)
+		_, err = srv.API().UpdateUser(ctx, user)
+		require.NoError(t, err)
+	}
+
+	t.Run("happy case", func(t *testing.T) {
+		user := someUser(t)
+		modified, err := srv.API().ModifyUser(ctx, user.ID, func(u *restuser.User) error {
+			u.Country = "fr"
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "fr", modified.Country)
+		assert.Equal(t, user.PasswordHash, modified.PasswordHash)
+	})
+
+	t.Run("retries on conflict", func(t *testing.T) {
+		user := someUser(t)
+		var calls int
+		modified, err := srv.API().ModifyUser(ctx, user.ID, func(u *restuser.User) error {
+			calls++
+			if calls == 1 {
+				updateConcurrently(t, u.ID)
+			}
+			u.Country = "fr"
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "fr", modified.Country)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		user := someUser(t)
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithModifyAttempts(2))
+		var calls int
+		_, err := api.ModifyUser(ctx, user.ID, func(u *restuser.User) error {
+			calls++
+			updateConcurrently(t, u.ID)
+			return nil
+		})
+		assert.True(t, errors.Is(err, restuser.ErrModifyAttemptsExhausted))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("modification fails", func(t *testing.T) {
+		user := someUser(t)
+		someErr := errors.New("can't modify")
+		_, err := srv.API().ModifyUser(ctx, user.ID, func(u *restuser.User) error {
+			u.Country = "fr"
+			return someErr
+		})
+		assert.Equal(t, someErr, err)
+
+		got, err := srv.API().GetUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "es", got.Country)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := srv.API().ModifyUser(ctx, "c3e11b46-109c-11eb-adc1-0242ac120002", func(u *restuser.User) error {
+			t.Fatal("modification should not be called")
+			return nil
+		})
+		assert.True(t, restuser.IsNotFound(err))
+	})
+}
@@ -0,0 +1,127 @@
+package restuser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UsersPage is a single page of the user listing.
+type UsersPage struct {
+	Users []User
+	// NextCursor should be provided as ListUsersParams.Cursor to retrieve the next page.
+	// It is empty if this is the last page.
+	NextCursor string
+}
+
+// ListUsersPage lists a single page of users, see ListUsers for details.
+func (a *API) ListUsersPage(ctx context.Context, params ListUsersParams) (*UsersPage, error) {
+	if err := params.validate(); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	resp, err := a.doRequest(ctx, opListUsers, usersPath, params.query(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var users []User
+		if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+			return nil, fmt.Errorf("response was %d, however can't unmarshal users JSON: %w", resp.StatusCode, err)
+		}
+		return &UsersPage{Users: users, NextCursor: nextCursor(resp.Header)}, nil
+	case http.StatusBadRequest,
+		http.StatusInternalServerError:
+		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return nil, a.retryAfterError(resp)
+	default:
+		return nil, a.unexpectedStatusError(resp)
+	}
+}
+
+// IterateUsers returns an iterator over all the users matching params, fetching the pages lazily.
+// The size of each page can be configured through params.Limit.
+func (a *API) IterateUsers(params ListUsersParams) *UserIterator {
+	return &UserIterator{api: a, params: params}
+}
+
+// UserIterator iterates over the pages of a user listing.
+// It is not safe for concurrent use.
+type UserIterator struct {
+	api    *API
+	params ListUsersParams
+
+	users []User
+	pos   int
+	last  bool
+	err   error
+}
+
+// Next advances the iterator to the next user, fetching the next page if needed.
+// It returns false when there are no more users, or when an error happened, which is provided by Err.
+// Iteration stops when the context is done.
+func (it *UserIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	for it.pos >= len(it.users) {
+		if it.last {
+			return false
+		}
+		page, err := it.api.ListUsersPage(ctx, it.params)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.users, it.pos = page.Users, 0
+		it.params.Cursor = page.NextCursor
+		it.last = page.NextCursor == ""
+	}
+	it.pos++
+	return true
+}
+
+// User returns the current user. It should be called only after Next returned true.
+func (it *UserIterator) User() User {
+	return it.users[it.pos-1]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *UserIterator) Err() error {
+	return it.err
+}
+
+// nextCursor extracts the cursor from the rel="next" link of the Link header.
+func nextCursor(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				if param = strings.TrimSpace(param); param != `rel="next"` && param != "rel=next" {
+					continue
+				}
+				u, err := url.Parse(target[1 : len(target)-1])
+				if err != nil {
+					return ""
+				}
+				return u.Query().Get("cursor")
+			}
+		}
+	}
+	return ""
+}
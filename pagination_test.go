@@ -0,0 +1,145 @@
+package restuser_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_ListUsersPage(t *testing.T) {
+	someUser := restuser.User{ID: "c3e11b46-109c-11eb-adc1-0242ac120002", Country: "es"}
+
+	for _, tc := range []struct {
+		name               string
+		link               string
+		expectedNextCursor string
+	}{
+		{
+			name:               "next page",
+			link:               `<http://localhost:8080/v1/users?country=es&cursor=abc&limit=1>; rel="next"`,
+			expectedNextCursor: "abc",
+		},
+		{
+			name:               "next page among other links",
+			link:               `<http://localhost:8080/v1/users?limit=1>; rel="first", </v1/users?cursor=def&limit=1>; rel="next"`,
+			expectedNextCursor: "def",
+		},
+		{
+			name:               "last page",
+			link:               "",
+			expectedNextCursor: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/v1/users?country=es&cursor=xyz&limit=1", req.RequestURI)
+				if tc.link != "" {
+					rw.Header().Set("Link", tc.link)
+				}
+				require.NoError(t, json.NewEncoder(rw).Encode([]restuser.User{someUser}))
+			}))
+			defer srv.Close()
+
+			api := restuser.New(restuser.Config{URL: srv.URL})
+			page, err := api.ListUsersPage(context.Background(), restuser.ListUsersParams{Country: "es", Limit: 1, Cursor: "xyz"})
+			require.NoError(t, err)
+			assert.Equal(t, &restuser.UsersPage{Users: []restuser.User{someUser}, NextCursor: tc.expectedNextCursor}, page)
+		})
+	}
+}
+
+func TestUserIterator(t *testing.T) {
+	const totalUsers = 5
+
+	// pagedServer serves totalUsers users, using the position of the next one as cursor.
+	pagedServer := func(t *testing.T, requests *int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			*requests++
+			limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+			require.NoError(t, err)
+			from := 0
+			if cursor := req.URL.Query().Get("cursor"); cursor != "" {
+				from, err = strconv.Atoi(cursor)
+				require.NoError(t, err)
+			}
+			to := from + limit
+			if to < totalUsers {
+				rw.Header().Set("Link", fmt.Sprintf(`</v1/users?cursor=%d&limit=%d>; rel="next"`, to, limit))
+			} else {
+				to = totalUsers
+			}
+			users := []restuser.User{}
+			for i := from; i < to; i++ {
+				users = append(users, restuser.User{ID: strconv.Itoa(i)})
+			}
+			require.NoError(t, json.NewEncoder(rw).Encode(users))
+		}))
+	}
+
+	t.Run("iterates all pages", func(t *testing.T) {
+		var requests int
+		srv := pagedServer(t, &requests)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL})
+		it := api.IterateUsers(restuser.ListUsersParams{Limit: 2})
+
+		var ids []string
+		for it.Next(context.Background()) {
+			ids = append(ids, it.User().ID)
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, []string{"0", "1", "2", "3", "4"}, ids)
+		assert.Equal(t, 3, requests)
+	})
+
+	t.Run("fetches pages lazily", func(t *testing.T) {
+		var requests int
+		srv := pagedServer(t, &requests)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL})
+		it := api.IterateUsers(restuser.ListUsersParams{Limit: 2})
+		require.True(t, it.Next(context.Background()))
+		require.True(t, it.Next(context.Background()))
+		assert.Equal(t, 1, requests)
+		require.True(t, it.Next(context.Background()))
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("stops on context cancellation", func(t *testing.T) {
+		var requests int
+		srv := pagedServer(t, &requests)
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		api := restuser.New(restuser.Config{URL: srv.URL})
+		it := api.IterateUsers(restuser.ListUsersParams{Limit: 2})
+		require.True(t, it.Next(ctx))
+		cancel()
+		assert.False(t, it.Next(ctx))
+		assert.Equal(t, context.Canceled, it.Err())
+		assert.False(t, it.Next(context.Background()), "iterator should remain stopped")
+	})
+
+	t.Run("stops on error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusInternalServerError)
+			require.NoError(t, json.NewEncoder(rw).Encode(restuser.ErrorResponse{Message: "everything is wrong"}))
+		}))
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL})
+		it := api.IterateUsers(restuser.ListUsersParams{})
+		assert.False(t, it.Next(context.Background()))
+		assert.True(t, restuser.IsInternal(it.Err()))
+	})
+}
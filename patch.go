@@ -0,0 +1,68 @@
+package restuser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// mergePatchContentType is the media type of the JSON Merge Patch documents (RFC 7396).
+const mergePatchContentType = "application/merge-patch+json"
+
+// PatchUser modifies only the fields of the user provided in the patch.
+// If ifUpdatedAt is not empty, the user is only modified if its UpdatedAt field still has that value,
+// returning an error matching ErrPreconditionFailed otherwise. It's sent as the ETag of the user, defined by the contract.
+// Without it, the service applies the patch again if the user is updated concurrently,
+// returning an error matching ErrConflict if it keeps being updated.
+// @Summary Partially update a user with the given ID.
+// @Description The body is a JSON Merge Patch (RFC 7396): only the fields provided are modified, and null clears them.
+// @Description The `id`, `created_at`, `updated_at`, `password_hash` and `password_salt` fields can't be modified,
+// @Description and the `password` can't be cleared.
+// @ID patch-user
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the user, to modify it only if it was not modified since it was retrieved. Compared using the strong comparison"
+// @Param patch body UserPatch true "Fields to modify"
+// @Success 200 {object} User
+// @Header 200 {string} ETag "Version of the user, its updated_at enclosed in double quotes, as a strong validator"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "If If-Match is not provided and the user keeps being updated concurrently"
+// @Failure 412 {object} ErrorResponse "If the ETag provided as If-Match doesn't match, or the user was updated concurrently"
+// @Failure 415 {object} ErrorResponse "If the body is not provided as application/merge-patch+json"
+// @Failure 429 {object} ErrorResponse "Too many requests, retry after the time provided in the Retry-After header"
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service unavailable, retry after the time provided in the Retry-After header"
+// @Header 429 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Header 503 {string} Retry-After "Seconds or HTTP date after which the request can be retried"
+// @Router /users/{id} [patch]
+func (a *API) PatchUser(ctx context.Context, id string, patch UserPatch, ifUpdatedAt string) (*User, error) {
+	header := http.Header{"Content-Type": {mergePatchContentType}}
+	if ifUpdatedAt != "" {
+		header.Set("If-Match", `"`+ifUpdatedAt+`"`)
+	}
+	// invalidated after the request, so users retrieved concurrently are not cached
+	defer a.uncacheUser(id)
+	resp, err := a.doRequest(ctx, opPatchUser, fmt.Sprintf("%s/%s", usersPath, id), nil, header, patch)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return a.unmarshalUserResponse(resp)
+	case http.StatusBadRequest,
+		http.StatusNotFound,
+		http.StatusConflict,
+		http.StatusPreconditionFailed,
+		http.StatusUnsupportedMediaType,
+		http.StatusInternalServerError:
+		return nil, a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return nil, a.retryAfterError(resp)
+	default:
+		return nil, a.unexpectedStatusError(resp)
+	}
+}
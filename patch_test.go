@@ -0,0 +1,88 @@
+package restuser_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_PatchUser(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	api := srv.API()
+
+	created, err := api.CreateUser(ctx, &restuser.User{
+		FirstName: "Francisco",
+		LastName:  "Johnson",
+		Name:      "pepe",
+		Email:     "pepe@faceit.com",
+		Password:  "password123",
+		Country:   "es",
+	})
+	require.NoError(t, err)
+
+	t.Run("modifies only the provided fields", func(t *testing.T) {
+		patched, err := api.PatchUser(ctx, created.ID, restuser.UserPatch{
+			Country:  restuser.String("fr"),
+			LastName: restuser.String(""),
+		}, created.UpdatedAt)
+		require.NoError(t, err)
+
+		expected := *created
+		expected.Country = "fr"
+		expected.LastName = ""
+		expected.UpdatedAt = patched.UpdatedAt
+		assert.Equal(t, &expected, patched)
+		assert.NotEqual(t, created.UpdatedAt, patched.UpdatedAt)
+	})
+
+	t.Run("modifies the password", func(t *testing.T) {
+		patched, err := api.PatchUser(ctx, created.ID, restuser.UserPatch{Password: restuser.String("password456")}, "")
+		require.NoError(t, err)
+		assert.NotEqual(t, created.PasswordHash, patched.PasswordHash)
+		assert.Empty(t, patched.Password)
+
+		_, err = api.PatchUser(ctx, created.ID, restuser.UserPatch{Password: restuser.String("")}, "")
+		assert.True(t, restuser.IsBadRequest(err))
+	})
+
+	t.Run("fails if the user was modified", func(t *testing.T) {
+		_, err := api.PatchUser(ctx, created.ID, restuser.UserPatch{Name: restuser.String("stale")}, created.UpdatedAt)
+		assert.True(t, restuser.IsPreconditionFailed(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := api.PatchUser(ctx, "c3e11b46-109c-11eb-adc1-0242ac120002", restuser.UserPatch{}, "")
+		assert.True(t, restuser.IsNotFound(err))
+	})
+}
+
+func TestAPI_PatchUser_Request(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPatch, req.Method)
+		assert.Equal(t, "/v1/users/c3e11b46-109c-11eb-adc1-0242ac120002", req.URL.Path)
+		assert.Equal(t, "application/merge-patch+json", req.Header.Get("Content-Type"))
+		assert.Equal(t, `"2006-01-02T15:04:05Z"`, req.Header.Get("If-Match"))
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"pepito","email":""}`, string(body))
+		require.NoError(t, json.NewEncoder(rw).Encode(restuser.User{}))
+	}))
+	defer srv.Close()
+
+	api := restuser.New(restuser.Config{URL: srv.URL})
+	_, err := api.PatchUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002", restuser.UserPatch{
+		Name:  restuser.String("pepito"),
+		Email: restuser.String(""),
+	}, "2006-01-02T15:04:05Z")
+	require.NoError(t, err)
+}
@@ -0,0 +1,64 @@
+package restuser
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRateLimit configures the API to perform at most rps requests per second, allowing bursts of up to burst requests.
+// The limit is shared by all the goroutines using the API, and each retry counts as a request.
+// Requests wait for their turn until the context is done.
+func WithRateLimit(rps float64, burst int) Option {
+	if burst < 1 {
+		burst = 1
+	}
+	return func(api *API) {
+		if rps <= 0 {
+			api.limiter = nil
+			return
+		}
+		api.limiter = &rateLimiter{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	}
+}
+
+// rateLimiter is a token bucket, refilled at rate tokens per second up to burst tokens.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token from the bucket, waiting until it's available or the context is done.
+// Tokens can be reserved in advance, leaving the bucket with a negative amount, so requests are served in order.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// give back the reserved token, so the following requests don't wait for it
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	const id = "c3e11b46-109c-11eb-adc1-0242ac120002"
+
+	var called int64
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&called, 1)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	t.Run("paces requests across goroutines", func(t *testing.T) {
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRateLimit(100, 2))
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, api.DeleteUser(context.Background(), id))
+			}()
+		}
+		wg.Wait()
+
+		// 2 requests are served by the burst, the other 4 need 10ms each
+		assert.True(t, time.Since(start) >= 40*time.Millisecond, "took %s", time.Since(start))
+	})
+
+	t.Run("stops waiting when context is done", func(t *testing.T) {
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRateLimit(1, 1))
+		require.NoError(t, api.DeleteUser(context.Background(), id))
+
+		atomic.StoreInt64(&called, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := api.DeleteUser(ctx, id)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, int64(0), atomic.LoadInt64(&called))
+	})
+}
@@ -0,0 +1,156 @@
+// Package restusermetrics instruments the restuser client with Prometheus metrics.
+//
+// It lives in its own module, so the client doesn't depend on Prometheus.
+package restusermetrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	operationLabel   = "operation"
+	statusClassLabel = "status_class"
+
+	// unknownOperation labels the requests that don't match any operation of the contract.
+	unknownOperation = "unknown"
+	// errorStatusClass labels the requests that didn't receive a response.
+	errorStatusClass = "error"
+)
+
+// Metrics holds the Prometheus collectors for the requests performed to the user service.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// New creates the metrics and registers them in the given registerer:
+//   - restuser_client_requests_total counter, labelled by operation and status_class.
+//   - restuser_client_request_duration_seconds histogram, labelled by operation and status_class.
+//   - restuser_client_requests_in_flight gauge, labelled by operation.
+//
+// The operation label is the swagger operation ID, like "get-user", and status_class is like "2xx",
+// or "error" if no response was received.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "restuser",
+			Subsystem: "client",
+			Name:      "requests_total",
+			Help:      "Requests performed to the user service.",
+		}, []string{operationLabel, statusClassLabel}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "restuser",
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the requests performed to the user service, until the response headers are received.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{operationLabel, statusClassLabel}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "restuser",
+			Subsystem: "client",
+			Name:      "requests_in_flight",
+			Help:      "Requests to the user service waiting for a response.",
+		}, []string{operationLabel}),
+	}
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.inFlight} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// RoundTripper wraps next, http.DefaultTransport if nil, recording the metrics of each request.
+func (m *Metrics) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		operation := Operation(req.Method, req.URL.Path)
+		inFlight := m.inFlight.WithLabelValues(operation)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		statusClass := errorStatusClass
+		if err == nil {
+			statusClass = StatusClass(resp.StatusCode)
+		}
+		m.requests.WithLabelValues(operation, statusClass).Inc()
+		m.duration.WithLabelValues(operation, statusClass).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+// WithHTTPClient configures the API to use the given client, recording the metrics of its requests.
+// The client provided is not modified, and http.DefaultClient, the default one of restuser.New, is used if it's nil.
+func (m *Metrics) WithHTTPClient(client *http.Client) restuser.Option {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	instrumented := *client
+	instrumented.Transport = m.RoundTripper(client.Transport)
+	return restuser.WithHTTPClient(&instrumented)
+}
+
+// Operation resolves the swagger operation ID from the method and the path of a request, matching it against
+// the path templates of the contract, so the base path is not relevant.
+// It returns "unknown" if no operation matches.
+func Operation(method, path string) string {
+	path = strings.TrimSuffix(path, "/")
+	segments := strings.Split(path, "/")
+	switch {
+	case method == http.MethodPost && strings.HasPrefix(segments[len(segments)-1], "users:"):
+		// /users:batchGet, /users:batchCreate and /users:batchDelete
+		switch strings.TrimPrefix(segments[len(segments)-1], "users:") {
+		case "batchGet":
+			return "batch-get-users"
+		case "batchCreate":
+			return "batch-create-users"
+		case "batchDelete":
+			return "batch-delete-users"
+		}
+	case segments[len(segments)-1] == "users":
+		// /users
+		switch method {
+		case http.MethodGet:
+			return "list-users"
+		case http.MethodPost:
+			return "post-user"
+		}
+	case len(segments) >= 2 && segments[len(segments)-2] == "users":
+		// /users/{id}
+		switch method {
+		case http.MethodGet:
+			return "get-user"
+		case http.MethodPut:
+			return "put-user"
+		case http.MethodPatch:
+			return "patch-user"
+		case http.MethodDelete:
+			return "delete-user"
+		}
+	}
+	return unknownOperation
+}
+
+// StatusClass returns the class of the status code, like "2xx".
+func StatusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return string(rune('0'+statusCode/100)) + "xx"
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
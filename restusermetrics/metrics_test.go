@@ -0,0 +1,94 @@
+package restusermetrics_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusermetrics"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	metrics, err := restusermetrics.New(reg)
+	require.NoError(t, err)
+
+	api := restuser.New(restuser.Config{URL: srv.URL}, metrics.WithHTTPClient(nil))
+	created, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+	_, err = api.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	_, err = api.GetUser(ctx, "c3e11b46-109c-11eb-adc1-0242ac120002")
+	require.Error(t, err)
+
+	failing := restuser.New(restuser.Config{URL: "http://127.0.0.1:1"}, metrics.WithHTTPClient(http.DefaultClient))
+	_, err = failing.ListUsers(ctx, restuser.ListUsersParams{})
+	require.Error(t, err)
+
+	expected := `
+# HELP restuser_client_requests_total Requests performed to the user service.
+# TYPE restuser_client_requests_total counter
+restuser_client_requests_total{operation="get-user",status_class="2xx"} 1
+restuser_client_requests_total{operation="get-user",status_class="4xx"} 1
+restuser_client_requests_total{operation="list-users",status_class="error"} 1
+restuser_client_requests_total{operation="post-user",status_class="2xx"} 1
+# HELP restuser_client_requests_in_flight Requests to the user service waiting for a response.
+# TYPE restuser_client_requests_in_flight gauge
+restuser_client_requests_in_flight{operation="get-user"} 0
+restuser_client_requests_in_flight{operation="list-users"} 0
+restuser_client_requests_in_flight{operation="post-user"} 0
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"restuser_client_requests_total",
+		"restuser_client_requests_in_flight",
+	))
+	assert.Equal(t, 4, testutil.CollectAndCount(reg, "restuser_client_request_duration_seconds"))
+
+	t.Run("already registered", func(t *testing.T) {
+		_, err := restusermetrics.New(reg)
+		assert.Error(t, err)
+	})
+}
+
+func TestOperation(t *testing.T) {
+	for _, tc := range []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{method: http.MethodPost, path: "/v1/users", expected: "post-user"},
+		{method: http.MethodGet, path: "/v1/users", expected: "list-users"},
+		{method: http.MethodGet, path: "/preproduction/v1/users/", expected: "list-users"},
+		{method: http.MethodGet, path: "/v1/users/c3e11b46-109c-11eb-adc1-0242ac120002", expected: "get-user"},
+		{method: http.MethodPut, path: "/v1/users/c3e11b46-109c-11eb-adc1-0242ac120002", expected: "put-user"},
+		{method: http.MethodPatch, path: "/v1/users/c3e11b46-109c-11eb-adc1-0242ac120002", expected: "patch-user"},
+		{method: http.MethodDelete, path: "/v1/users/c3e11b46-109c-11eb-adc1-0242ac120002", expected: "delete-user"},
+		{method: http.MethodPost, path: "/v1/users:batchGet", expected: "batch-get-users"},
+		{method: http.MethodPost, path: "/v1/users:batchCreate", expected: "batch-create-users"},
+		{method: http.MethodPost, path: "/v1/users:batchDelete", expected: "batch-delete-users"},
+		{method: http.MethodPost, path: "/v1/users:batchUpdate", expected: "unknown"},
+		{method: http.MethodDelete, path: "/v1/users", expected: "unknown"},
+		{method: http.MethodGet, path: "/v1/something/else", expected: "unknown"},
+		{method: http.MethodGet, path: "/", expected: "unknown"},
+	} {
+		assert.Equal(t, tc.expected, restusermetrics.Operation(tc.method, tc.path), "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", restusermetrics.StatusClass(http.StatusNoContent))
+	assert.Equal(t, "4xx", restusermetrics.StatusClass(http.StatusConflict))
+	assert.Equal(t, "5xx", restusermetrics.StatusClass(http.StatusBadGateway))
+	assert.Equal(t, "unknown", restusermetrics.StatusClass(1000))
+}
@@ -0,0 +1,101 @@
+// Package restuserotel instruments the restuser client with OpenTelemetry.
+//
+// It lives in its own module, so the client doesn't depend on OpenTelemetry.
+package restuserotel
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/a-faceit-candidate/restuser/restuserotel"
+
+// OperationKey is the attribute holding the swagger operation ID, like "get-user".
+const OperationKey = attribute.Key("restuser.operation")
+
+// WithTracerProvider configures the API to create a client span for each operation, named as its swagger operation ID,
+// and to inject the W3C trace context headers in each request.
+func WithTracerProvider(tp trace.TracerProvider) restuser.Option {
+	tracer := tp.Tracer(instrumentationName)
+	propagator := propagation.TraceContext{}
+
+	tracing := func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+		return func(ctx context.Context) (*http.Response, error) {
+			ctx, span := tracer.Start(ctx, op.ID,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(OperationKey.String(op.ID), semconv.HTTPRequestMethodKey.String(op.Method)),
+			)
+			defer span.End()
+
+			resp, err := next(ctx)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+			return resp, nil
+		}
+	}
+	inject := func(ctx context.Context, req *http.Request) error {
+		propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+		return nil
+	}
+
+	return func(api *restuser.API) {
+		restuser.WithMiddleware(tracing)(api)
+		restuser.WithRequestEditor(inject)(api)
+	}
+}
+
+// WithMeterProvider configures the API to record the duration of each operation in the
+// "restuser.client.duration" histogram, and count the responses by status code in the
+// "restuser.client.responses" counter. Both have the operation ID in the OperationKey attribute.
+// Responses are the ones received after the retries, if any, and network errors are counted with status code 0.
+func WithMeterProvider(mp metric.MeterProvider) restuser.Option {
+	meter := mp.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram("restuser.client.duration",
+		metric.WithDescription("Duration of the restuser operations, including retries."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	responses, err := meter.Int64Counter("restuser.client.responses",
+		metric.WithDescription("Responses received by the restuser operations, by status code."),
+		metric.WithUnit("{response}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	measuring := func(op restuser.Operation, next restuser.Invoker) restuser.Invoker {
+		return func(ctx context.Context) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(ctx)
+
+			statusCode := 0
+			if err == nil {
+				statusCode = resp.StatusCode
+			}
+			attrs := metric.WithAttributes(OperationKey.String(op.ID), semconv.HTTPResponseStatusCode(statusCode))
+			duration.Record(ctx, time.Since(start).Seconds(), attrs)
+			responses.Add(ctx, 1, attrs)
+			return resp, err
+		}
+	}
+	return restuser.WithMiddleware(measuring)
+}
@@ -0,0 +1,111 @@
+package restuserotel_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restuserotel"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var traceparent string
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		traceparent = req.Header.Get("traceparent")
+		srv.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer proxy.Close()
+
+	api := restuser.New(restuser.Config{URL: proxy.URL}, restuserotel.WithTracerProvider(tp))
+
+	created, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+	_, err = api.GetUser(ctx, "c3e11b46-109c-11eb-adc1-0242ac120002")
+	require.Error(t, err)
+	require.NoError(t, api.DeleteUser(ctx, created.ID))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for i, expected := range []struct {
+		name       string
+		method     string
+		statusCode int
+		status     codes.Code
+	}{
+		{name: "post-user", method: http.MethodPost, statusCode: http.StatusCreated, status: codes.Unset},
+		{name: "get-user", method: http.MethodGet, statusCode: http.StatusNotFound, status: codes.Error},
+		{name: "delete-user", method: http.MethodDelete, statusCode: http.StatusNoContent, status: codes.Unset},
+	} {
+		span := spans[i]
+		assert.Equal(t, expected.name, span.Name())
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+		assert.Equal(t, expected.status, span.Status().Code)
+		assert.Contains(t, span.Attributes(), restuserotel.OperationKey.String(expected.name))
+		assert.Contains(t, span.Attributes(), semconv.HTTPRequestMethodKey.String(expected.method))
+		assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(expected.statusCode))
+	}
+
+	lastSpan := spans[2].SpanContext()
+	assert.Equal(t, "00-"+lastSpan.TraceID().String()+"-"+lastSpan.SpanID().String()+"-01", traceparent)
+}
+
+func TestWithMeterProvider(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuserotel.WithMeterProvider(mp))
+
+	for i := 0; i < 2; i++ {
+		_, err := api.GetUser(ctx, "c3e11b46-109c-11eb-adc1-0242ac120002")
+		require.Error(t, err)
+	}
+	_, err := api.ListUsers(ctx, restuser.ListUsersParams{})
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	responses := metrics["restuser.client.responses"].Data.(metricdata.Sum[int64])
+	counts := map[attribute.Set]int64{}
+	for _, dp := range responses.DataPoints {
+		counts[dp.Attributes] = dp.Value
+	}
+	assert.Equal(t, map[attribute.Set]int64{
+		attribute.NewSet(restuserotel.OperationKey.String("get-user"), semconv.HTTPResponseStatusCode(http.StatusNotFound)): 2,
+		attribute.NewSet(restuserotel.OperationKey.String("list-users"), semconv.HTTPResponseStatusCode(http.StatusOK)):     1,
+	}, counts)
+
+	duration := metrics["restuser.client.duration"].Data.(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 2)
+	for _, dp := range duration.DataPoints {
+		assert.True(t, dp.Sum > 0)
+	}
+	assert.Equal(t, "s", metrics["restuser.client.duration"].Unit)
+}
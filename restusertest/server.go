@@ -0,0 +1,29 @@
+// Package restusertest provides an in-memory implementation of the user service for testing purposes.
+package restusertest
+
+import (
+	"net/http/httptest"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/server"
+)
+
+// Server is a stateful in-memory user service, listening on a local loopback address.
+type Server struct {
+	*httptest.Server
+	api *restuser.API
+}
+
+// NewServer starts and returns a new Server, which should be closed when finished.
+func NewServer() *Server {
+	s := &Server{
+		Server: httptest.NewServer(server.NewHandler(server.NewMemoryStore())),
+	}
+	s.api = restuser.New(restuser.Config{URL: s.URL})
+	return s
+}
+
+// API returns a client configured to use this server.
+func (s *Server) API() *restuser.API {
+	return s.api
+}
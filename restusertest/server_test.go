@@ -0,0 +1,103 @@
+package restusertest_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	api := srv.API()
+
+	created, err := api.CreateUser(ctx, &restuser.User{
+		FirstName: "Francisco",
+		LastName:  "Johnson",
+		Name:      "pepe",
+		Email:     "pepe@faceit.com",
+		Password:  "password123",
+		Country:   "es",
+	})
+	require.NoError(t, err)
+	assert.Len(t, created.ID, 36)
+	assert.NotEmpty(t, created.CreatedAt)
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+	assert.Empty(t, created.Password)
+	hash := sha256.Sum256([]byte("password123" + created.PasswordSalt))
+	assert.Equal(t, hex.EncodeToString(hash[:]), created.PasswordHash)
+
+	got, err := api.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+
+	t.Run("create validation", func(t *testing.T) {
+		_, err := api.CreateUser(ctx, &restuser.User{ID: "foo", Password: "password123"})
+		assert.True(t, restuser.IsBadRequest(err))
+
+		_, err = api.CreateUser(ctx, &restuser.User{Password: "short"})
+		assert.True(t, restuser.IsBadRequest(err))
+	})
+
+	t.Run("list hides password fields and filters by country", func(t *testing.T) {
+		_, err := api.CreateUser(ctx, &restuser.User{Name: "pierre", Password: "password123", Country: "fr"})
+		require.NoError(t, err)
+
+		users, err := api.ListUsers(ctx, restuser.ListUsersParams{Country: "es"})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, created.ID, users[0].ID)
+		assert.Empty(t, users[0].PasswordHash)
+		assert.Empty(t, users[0].PasswordSalt)
+
+		all, err := api.ListUsers(ctx, restuser.ListUsersParams{})
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("list paginates", func(t *testing.T) {
+		it := api.IterateUsers(restuser.ListUsersParams{Limit: 1})
+		var count int
+		for it.Next(ctx) {
+			count++
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		toUpdate := *got
+		toUpdate.Country = "pt"
+		updated, err := api.UpdateUser(ctx, &toUpdate)
+		require.NoError(t, err)
+		assert.Equal(t, "pt", updated.Country)
+		assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+		assert.NotEqual(t, created.UpdatedAt, updated.UpdatedAt)
+		assert.Equal(t, created.PasswordHash, updated.PasswordHash, "password should not change if empty")
+
+		_, err = api.UpdateUser(ctx, &toUpdate)
+		assert.True(t, restuser.IsConflict(err), "UpdatedAt is stale now")
+
+		missing := toUpdate
+		missing.ID = "c3e11b46-109c-11eb-adc1-0242ac120002"
+		_, err = api.UpdateUser(ctx, &missing)
+		assert.True(t, restuser.IsNotFound(err))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, api.DeleteUser(ctx, created.ID))
+
+		_, err := api.GetUser(ctx, created.ID)
+		assert.True(t, restuser.IsNotFound(err))
+
+		err = api.DeleteUser(ctx, created.ID)
+		assert.True(t, restuser.IsNotFound(err))
+	})
+}
@@ -0,0 +1,173 @@
+package restuser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how the failed requests are retried.
+// When provided to WithRetryPolicy, the zero values of MaxAttempts, InitialBackoff, MaxBackoff, Multiplier,
+// RetryableStatusCodes and RetryableError are replaced by the ones from DefaultRetryPolicy.
+// Jitter and RetryNonIdempotent are kept as provided, so a zero Jitter means no jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts performed for each call, including the first one.
+	// Negative values are treated as 1, so the requests are not retried.
+	MaxAttempts int
+	// InitialBackoff is the time waited before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the time waited between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff is multiplied by after each retry.
+	// Values below 1 are treated as 1, so the backoff never decreases.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each backoff that is randomized.
+	// Values outside that range are clamped to it.
+	Jitter float64
+	// RetryableStatusCodes are the response status codes that cause the request to be retried.
+	RetryableStatusCodes []int
+	// RetryableError decides whether a network error causes the request to be retried.
+	// Errors caused by the context being done are never retried.
+	RetryableError func(error) bool
+	// RetryNonIdempotent allows retrying non-idempotent operations, like CreateUser.
+	// Retrying them may cause duplicates if the service processed a request whose response was lost.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the RetryPolicy used to complete the zero values of the provided one.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryableError: IsTemporaryNetworkError,
+	}
+}
+
+// WithRetryPolicy configures the API to retry the failed requests according to the given policy.
+// By default requests are not retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	defaults := DefaultRetryPolicy()
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = defaults.InitialBackoff
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = defaults.MaxBackoff
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = defaults.Multiplier
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 1
+	}
+	policy.Jitter = math.Max(0, math.Min(1, policy.Jitter))
+	if policy.RetryableStatusCodes == nil {
+		policy.RetryableStatusCodes = defaults.RetryableStatusCodes
+	}
+	if policy.RetryableError == nil {
+		policy.RetryableError = defaults.RetryableError
+	}
+	return func(api *API) {
+		api.retryPolicy = &policy
+	}
+}
+
+// IsTemporaryNetworkError reports whether err is a network error that is worth retrying:
+// timeouts, refused or reset connections and connections closed before receiving the response.
+func IsTemporaryNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// attemptsFor returns the maximum attempts that can be performed for the given operation.
+func (p *RetryPolicy) attemptsFor(op Operation) int {
+	if p == nil || (!op.Idempotent && !p.RetryNonIdempotent) {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if p == nil || ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen) && p.RetryableError(err)
+	}
+	// don't retry if the service asked to wait longer than we would
+	if retryAfter, ok := parseRetryAfter(resp.Header, time.Now()); ok && retryAfter > p.MaxBackoff {
+		return false
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// wait blocks for the backoff corresponding to the given attempt, or until the context is done.
+// If the response provided the Retry-After header and it's longer than the backoff, that time is waited instead.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int, resp *http.Response) error {
+	backoff := p.backoff(attempt)
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header, time.Now()); ok && retryAfter > backoff {
+			backoff = retryAfter
+		}
+	}
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+// drainAndClose reads the rest of the body, so the connection can be reused, and closes it.
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, 4096))
+	_ = body.Close()
+}
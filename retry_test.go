@@ -0,0 +1,194 @@
+package restuser_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	someUser := &restuser.User{
+		ID:        "c3e11b46-109c-11eb-adc1-0242ac120002",
+		CreatedAt: "2006-01-02T15:04:05Z",
+		UpdatedAt: "2006-01-03T15:04:05Z",
+		FirstName: "Francisco",
+		LastName:  "Johnson",
+		Name:      "pepe",
+		Password:  "password123",
+		Email:     "pepe@faceit.com",
+		Country:   "fr",
+	}
+	someErrorResponse := &restuser.ErrorResponse{Message: "everything is wrong"}
+	somePolicy := restuser.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	// failingServer responds 500 to the first failures requests, and 200 with the decoded body afterwards.
+	failingServer := func(t *testing.T, failures int64, called *int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt64(called, 1) <= failures {
+				rw.WriteHeader(http.StatusInternalServerError)
+				require.NoError(t, json.NewEncoder(rw).Encode(someErrorResponse))
+				return
+			}
+			if req.Method == http.MethodPut {
+				var got restuser.User
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&got))
+				assert.Equal(t, someUser, &got)
+			}
+			require.NoError(t, json.NewEncoder(rw).Encode(someUser))
+		}))
+	}
+
+	t.Run("retries idempotent operations rebuilding the body", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 2, &called)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(somePolicy))
+		res, err := api.UpdateUser(context.Background(), someUser)
+		require.NoError(t, err)
+		assert.Equal(t, someUser, res)
+		assert.Equal(t, int64(3), atomic.LoadInt64(&called))
+	})
+
+	t.Run("returns the last response when attempts are exhausted", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 5, &called)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(somePolicy))
+		_, err := api.GetUser(context.Background(), someUser.ID)
+		assert.Equal(t, restuser.Error{StatusCode: http.StatusInternalServerError, Response: someErrorResponse}, err)
+		assert.Equal(t, int64(3), atomic.LoadInt64(&called))
+	})
+
+	t.Run("performs a single attempt when max attempts are negative", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 1, &called)
+		defer srv.Close()
+
+		policy := somePolicy
+		policy.MaxAttempts = -1
+		policy.Multiplier = -2
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(policy))
+		_, err := api.GetUser(context.Background(), someUser.ID)
+		assert.True(t, restuser.IsInternal(err))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&called))
+	})
+
+	t.Run("does not retry CreateUser by default", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 1, &called)
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(somePolicy))
+		_, err := api.CreateUser(context.Background(), someUser)
+		assert.Error(t, err)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&called))
+	})
+
+	t.Run("retries CreateUser when non-idempotent retries are allowed", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 1, &called)
+		defer srv.Close()
+
+		policy := somePolicy
+		policy.RetryNonIdempotent = true
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(policy))
+		_, err := api.CreateUser(context.Background(), someUser)
+		assert.Error(t, err, "service responds 200 instead of 201")
+		assert.Equal(t, int64(2), atomic.LoadInt64(&called))
+	})
+
+	t.Run("does not retry non retryable status codes", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 1, &called)
+		defer srv.Close()
+
+		policy := somePolicy
+		policy.RetryableStatusCodes = []int{http.StatusServiceUnavailable}
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(policy))
+		_, err := api.GetUser(context.Background(), someUser.ID)
+		assert.Error(t, err)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&called))
+	})
+
+	t.Run("retries network errors", func(t *testing.T) {
+		var called int64
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt64(&called, 1) == 1 {
+				conn, _, err := rw.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.NoError(t, conn.Close())
+				return
+			}
+			require.NoError(t, json.NewEncoder(rw).Encode(someUser))
+		}))
+		defer srv.Close()
+
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(somePolicy))
+		res, err := api.GetUser(context.Background(), someUser.ID)
+		require.NoError(t, err)
+		assert.Equal(t, someUser, res)
+		assert.Equal(t, int64(2), atomic.LoadInt64(&called))
+	})
+
+	t.Run("stops waiting when context is done", func(t *testing.T) {
+		var called int64
+		srv := failingServer(t, 5, &called)
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		policy := somePolicy
+		policy.InitialBackoff = time.Hour
+		policy.MaxBackoff = time.Hour
+		api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(policy))
+		_, err := api.GetUser(ctx, someUser.ID)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&called))
+	})
+	t.Run("honours Retry-After", func(t *testing.T) {
+		for _, tc := range []struct {
+			name       string
+			retryAfter string
+			called     int64
+		}{
+			{name: "shorter than max backoff", retryAfter: "1", called: 2},
+			{name: "longer than max backoff", retryAfter: "60", called: 1},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				var called int64
+				srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					if atomic.AddInt64(&called, 1) == 1 {
+						rw.Header().Set("Retry-After", tc.retryAfter)
+						rw.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					require.NoError(t, json.NewEncoder(rw).Encode(someUser))
+				}))
+				defer srv.Close()
+
+				policy := somePolicy
+				policy.MaxBackoff = 2 * time.Second
+				api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithRetryPolicy(policy))
+
+				start := time.Now()
+				_, _ = api.GetUser(context.Background(), someUser.ID)
+				assert.Equal(t, tc.called, atomic.LoadInt64(&called))
+				if tc.called > 1 {
+					assert.True(t, time.Since(start) >= time.Second, "should wait for Retry-After instead of the backoff")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package restuser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAmbiguous is returned when a lookup expecting a single user matches several ones.
+var ErrAmbiguous = errors.New("more than one user matches")
+
+// GetUserByEmail retrieves the only user with the given email.
+// It returns an error matching ErrNotFound if there's no such user, and ErrAmbiguous if there are several ones.
+// As in ListUsers, the PasswordHash and PasswordSalt fields are not provided.
+func (a *API) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("email can't be empty")
+	}
+	// the email is not included in the errors, since they're likely to be logged
+	return a.getUniqueUser(ctx, ListUsersParams{Email: email}, "the email provided")
+}
+
+// GetUserByName retrieves the only user with the given nickname.
+// It returns an error matching ErrNotFound if there's no such user, and ErrAmbiguous if there are several ones.
+// As in ListUsers, the PasswordHash and PasswordSalt fields are not provided.
+func (a *API) GetUserByName(ctx context.Context, name string) (*User, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name can't be empty")
+	}
+	return a.getUniqueUser(ctx, ListUsersParams{Name: name}, fmt.Sprintf("the name %q", name))
+}
+
+// getUniqueUser lists the users matching params, until a second one is found.
+// The description of the params is used in the errors.
+func (a *API) getUniqueUser(ctx context.Context, params ListUsersParams, description string) (*User, error) {
+	params.Limit = 2
+	it := a.IterateUsers(params)
+	var found []User
+	for len(found) < 2 && it.Next(ctx) {
+		found = append(found, it.User())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("user with %s: %w", description, ErrNotFound)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("users with %s: %w", description, ErrAmbiguous)
+	}
+}
@@ -0,0 +1,71 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_GetUserByEmailAndName(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	api := srv.API()
+
+	var created []*restuser.User
+	for _, email := range []string{"pepe@faceit.com", "pepe+support@faceit.com"} {
+		user, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Email: email, Password: "password123", Country: "es"})
+		require.NoError(t, err)
+		created = append(created, user)
+	}
+	other, err := api.CreateUser(ctx, &restuser.User{Name: "pierre", Email: "pierre@faceit.com", Password: "password123", Country: "fr"})
+	require.NoError(t, err)
+
+	t.Run("by email", func(t *testing.T) {
+		got, err := api.GetUserByEmail(ctx, "pepe+support@faceit.com")
+		require.NoError(t, err)
+		assert.Equal(t, created[1].ID, got.ID)
+		assert.Empty(t, got.PasswordHash)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		got, err := api.GetUserByName(ctx, "pierre")
+		require.NoError(t, err)
+		assert.Equal(t, other.ID, got.ID)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := api.GetUserByEmail(ctx, "nobody@faceit.com")
+		assert.True(t, restuser.IsNotFound(err))
+		_, err = api.GetUserByName(ctx, "pep")
+		assert.True(t, restuser.IsNotFound(err))
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, err := api.GetUserByName(ctx, "pepe")
+		assert.True(t, errors.Is(err, restuser.ErrAmbiguous))
+	})
+}
+
+func TestAPI_GetUserByEmailAndName_Empty(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	api := srv.API()
+
+	// with a single user, an unfiltered listing would return it
+	_, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Email: "pepe@faceit.com", Password: "password123", Country: "es"})
+	require.NoError(t, err)
+
+	got, err := api.GetUserByEmail(ctx, "")
+	assert.Error(t, err)
+	assert.Nil(t, got)
+	got, err = api.GetUserByName(ctx, "")
+	assert.Error(t, err)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+// maxBatchSize is the maximum amount of items of the batch operations.
+const maxBatchSize = 100
+
+func (h *Handler) batchGetUsers(rw http.ResponseWriter, req *http.Request) {
+	var batch restuser.BatchGetRequest
+	if !decodeBatch(rw, req, &batch, func() int { return len(batch.IDs) }) {
+		return
+	}
+
+	results := make([]restuser.BatchResult, len(batch.IDs))
+	for i, id := range batch.IDs {
+		user, err := h.store.Get(req.Context(), id)
+		if err != nil {
+			results[i] = errorResult(storeErrorStatus(err))
+			continue
+		}
+		user.PasswordHash = ""
+		user.PasswordSalt = ""
+		results[i] = restuser.BatchResult{Status: http.StatusOK, User: &user}
+	}
+	respond(rw, http.StatusOK, restuser.BatchResponse{Results: results})
+}
+
+func (h *Handler) batchCreateUsers(rw http.ResponseWriter, req *http.Request) {
+	var batch restuser.BatchCreateRequest
+	if !decodeBatch(rw, req, &batch, func() int { return len(batch.Users) }) || !validBatchMode(rw, batch.Mode) {
+		return
+	}
+	ctx := req.Context()
+	allOrNothing := batch.Mode == restuser.AllOrNothing
+
+	results := make([]restuser.BatchResult, len(batch.Users))
+	failed := false
+	for i, user := range batch.Users {
+		if err := validateCreate(user); err != nil {
+			results[i] = errorResult(http.StatusBadRequest, err.Error())
+			failed = true
+		}
+	}
+	if failed && allOrNothing {
+		respondAborted(rw, results)
+		return
+	}
+
+	var created []int
+	for i := range batch.Users {
+		if results[i].Status != 0 {
+			continue
+		}
+		user := batch.Users[i]
+		h.prepareCreate(&user)
+		if err := h.store.Create(ctx, user); err != nil {
+			results[i] = errorResult(storeErrorStatus(err))
+			if allOrNothing {
+				h.rollbackCreated(ctx, results, created)
+				respondAborted(rw, results)
+				return
+			}
+			continue
+		}
+		results[i] = restuser.BatchResult{Status: http.StatusCreated, User: &user}
+		created = append(created, i)
+	}
+	respond(rw, http.StatusOK, restuser.BatchResponse{Results: results})
+}
+
+// rollbackCreated deletes the users created by an all or nothing batch that failed, clearing their results.
+// Users that can't be deleted keep their results, since they still exist.
+func (h *Handler) rollbackCreated(ctx context.Context, results []restuser.BatchResult, created []int) {
+	for _, i := range created {
+		if err := h.store.Delete(ctx, results[i].User.ID); err == nil {
+			results[i] = restuser.BatchResult{}
+		}
+	}
+}
+
+func (h *Handler) batchDeleteUsers(rw http.ResponseWriter, req *http.Request) {
+	var batch restuser.BatchDeleteRequest
+	if !decodeBatch(rw, req, &batch, func() int { return len(batch.IDs) }) || !validBatchMode(rw, batch.Mode) {
+		return
+	}
+	ctx := req.Context()
+	allOrNothing := batch.Mode == restuser.AllOrNothing
+
+	results := make([]restuser.BatchResult, len(batch.IDs))
+	// stored users are retrieved to restore them if an all or nothing batch fails
+	stored := make([]restuser.User, len(batch.IDs))
+	if allOrNothing {
+		failed := false
+		for i, id := range batch.IDs {
+			user, err := h.store.Get(ctx, id)
+			if err != nil {
+				results[i] = errorResult(storeErrorStatus(err))
+				failed = true
+				continue
+			}
+			stored[i] = user
+		}
+		if failed {
+			respondAborted(rw, results)
+			return
+		}
+	}
+
+	var deleted []int
+	for i, id := range batch.IDs {
+		if err := h.store.Delete(ctx, id); err != nil {
+			results[i] = errorResult(storeErrorStatus(err))
+			if allOrNothing {
+				h.rollbackDeleted(ctx, results, stored, deleted)
+				respondAborted(rw, results)
+				return
+			}
+			continue
+		}
+		results[i] = restuser.BatchResult{Status: http.StatusNoContent}
+		deleted = append(deleted, i)
+	}
+	respond(rw, http.StatusOK, restuser.BatchResponse{Results: results})
+}
+
+// rollbackDeleted restores the users deleted by an all or nothing batch that failed, clearing their results.
+// Users that can't be restored keep their results, since they're still deleted.
+func (h *Handler) rollbackDeleted(ctx context.Context, results []restuser.BatchResult, stored []restuser.User, deleted []int) {
+	for _, i := range deleted {
+		if err := h.store.Create(ctx, stored[i]); err == nil {
+			results[i] = restuser.BatchResult{}
+		}
+	}
+}
+
+// decodeBatch decodes the batch request, responding the error if it's invalid or it has too many items.
+func decodeBatch(rw http.ResponseWriter, req *http.Request, batch interface{}, items func() int) bool {
+	if err := json.NewDecoder(req.Body).Decode(batch); err != nil {
+		respondError(rw, http.StatusBadRequest, fmt.Sprintf("invalid batch JSON: %s", err))
+		return false
+	}
+	if items() > maxBatchSize {
+		respondError(rw, http.StatusBadRequest, fmt.Sprintf("batch should have at most %d items", maxBatchSize))
+		return false
+	}
+	return true
+}
+
+func validBatchMode(rw http.ResponseWriter, mode restuser.BatchMode) bool {
+	switch mode {
+	case "", restuser.BestEffort, restuser.AllOrNothing:
+		return true
+	default:
+		respondError(rw, http.StatusBadRequest, fmt.Sprintf("unknown mode %q", mode))
+		return false
+	}
+}
+
+// respondAborted responds the results of an all or nothing batch that failed:
+// the items without a result are responded as failed because of the other ones.
+func respondAborted(rw http.ResponseWriter, results []restuser.BatchResult) {
+	for i := range results {
+		if results[i].Status == 0 {
+			results[i] = errorResult(http.StatusFailedDependency, "not applied because other items of the batch failed")
+		}
+	}
+	respond(rw, http.StatusOK, restuser.BatchResponse{Results: results})
+}
+
+func errorResult(status int, message string) restuser.BatchResult {
+	return restuser.BatchResult{Status: status, Error: &restuser.ErrorResponse{Message: message}}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+// listedFields are the JSON names of the fields provided in the user listing, which can be sorted by and selected.
+var listedFields = []string{"id", "created_at", "updated_at", "first_name", "last_name", "name", "email", "country"}
+
+// field returns the value of the user field with the given JSON name, which should be one of the listedFields.
+func field(user restuser.User, name string) string {
+	switch name {
+	case "id":
+		return user.ID
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	case "first_name":
+		return user.FirstName
+	case "last_name":
+		return user.LastName
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "country":
+		return user.Country
+	default:
+		panic(fmt.Errorf("unknown field %q", name))
+	}
+}
+
+// parseSort parses the comma separated sort keys, prefixed with "-" when descending.
+func parseSort(sort string) ([]restuser.SortKey, error) {
+	if sort == "" {
+		return nil, nil
+	}
+	var keys []restuser.SortKey
+	for _, name := range strings.Split(sort, ",") {
+		key := restuser.SortKey{Field: strings.TrimPrefix(name, "-"), Descending: strings.HasPrefix(name, "-")}
+		if !contains(listedFields, key.Field) {
+			return nil, fmt.Errorf("can't sort by unknown field %q", key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseFields parses the comma separated fields to respond.
+func parseFields(fields string) ([]string, error) {
+	if fields == "" {
+		return nil, nil
+	}
+	names := strings.Split(fields, ",")
+	for _, name := range names {
+		if !contains(listedFields, name) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	return names, nil
+}
+
+// sparse returns the fields of the user with the given names.
+func sparse(user restuser.User, names []string) map[string]string {
+	fields := make(map[string]string, len(names))
+	for _, name := range names {
+		fields[name] = field(user, name)
+	}
+	return fields
+}
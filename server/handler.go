@@ -0,0 +1,380 @@
+// Package server provides a reference implementation of the user service REST API contract.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+const (
+	defaultBasePath   = "/v1"
+	usersPath         = "/users"
+	minPasswordLength = 8
+)
+
+// Handler serves the user service REST API on top of a Store.
+type Handler struct {
+	store    Store
+	basePath string
+	now      func() time.Time
+}
+
+// NewHandler creates a new Handler serving the users from the given store.
+func NewHandler(store Store, options ...Option) *Handler {
+	h := &Handler{
+		store:    store,
+		basePath: defaultBasePath,
+		now:      time.Now,
+	}
+	for _, opt := range options {
+		opt(h)
+	}
+	return h
+}
+
+// Option configures the runtime specifics of the handler.
+type Option func(*Handler)
+
+// WithBasePath configures the Handler to serve the API on a different base path.
+func WithBasePath(basePath string) Option {
+	return func(h *Handler) {
+		h.basePath = basePath
+	}
+}
+
+// WithClock configures the function used to obtain the current time, for the CreatedAt and UpdatedAt fields.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	collection := h.basePath + usersPath
+	switch {
+	case req.URL.Path == collection:
+		switch req.Method {
+		case http.MethodPost:
+			h.createUser(rw, req)
+		case http.MethodGet:
+			h.listUsers(rw, req)
+		default:
+			methodNotAllowed(rw, http.MethodGet, http.MethodPost)
+		}
+	case strings.HasPrefix(req.URL.Path, collection+":"):
+		if req.Method != http.MethodPost {
+			methodNotAllowed(rw, http.MethodPost)
+			return
+		}
+		switch strings.TrimPrefix(req.URL.Path, collection+":") {
+		case "batchGet":
+			h.batchGetUsers(rw, req)
+		case "batchCreate":
+			h.batchCreateUsers(rw, req)
+		case "batchDelete":
+			h.batchDeleteUsers(rw, req)
+		default:
+			respondError(rw, http.StatusNotFound, "not found")
+		}
+	case strings.HasPrefix(req.URL.Path, collection+"/"):
+		id := strings.TrimPrefix(req.URL.Path, collection+"/")
+		switch req.Method {
+		case http.MethodGet:
+			h.getUser(rw, req, id)
+		case http.MethodPut:
+			h.updateUser(rw, req, id)
+		case http.MethodPatch:
+			h.patchUser(rw, req, id)
+		case http.MethodDelete:
+			h.deleteUser(rw, req, id)
+		default:
+			methodNotAllowed(rw, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+		}
+	default:
+		respondError(rw, http.StatusNotFound, "not found")
+	}
+}
+
+func (h *Handler) createUser(rw http.ResponseWriter, req *http.Request) {
+	var user restuser.User
+	if err := json.NewDecoder(req.Body).Decode(&user); err != nil {
+		respondError(rw, http.StatusBadRequest, fmt.Sprintf("invalid user JSON: %s", err))
+		return
+	}
+	if err := validateCreate(user); err != nil {
+		respondError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.prepareCreate(&user)
+	if err := h.store.Create(req.Context(), user); err != nil {
+		respondStoreError(rw, err)
+		return
+	}
+	respond(rw, http.StatusCreated, user)
+}
+
+func (h *Handler) updateUser(rw http.ResponseWriter, req *http.Request, id string) {
+	var user restuser.User
+	if err := json.NewDecoder(req.Body).Decode(&user); err != nil {
+		respondError(rw, http.StatusBadRequest, fmt.Sprintf("invalid user JSON: %s", err))
+		return
+	}
+	if user.ID != "" && user.ID != id {
+		respondError(rw, http.StatusBadRequest, "id can't be updated")
+		return
+	}
+	if user.Password != "" {
+		if err := validatePassword(user.Password); err != nil {
+			respondError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	stored, err := h.store.Get(req.Context(), id)
+	if err != nil {
+		respondStoreError(rw, err)
+		return
+	}
+	if user.UpdatedAt != stored.UpdatedAt {
+		respondError(rw, http.StatusConflict, "user was updated since it was retrieved")
+		return
+	}
+
+	user.ID = stored.ID
+	user.CreatedAt = stored.CreatedAt
+	user.UpdatedAt = h.timestamp()
+	if user.Password != "" {
+		setPassword(&user)
+	} else {
+		user.PasswordHash = stored.PasswordHash
+		user.PasswordSalt = stored.PasswordSalt
+	}
+	if err := h.store.Update(req.Context(), user, stored.UpdatedAt); err != nil {
+		respondStoreError(rw, err)
+		return
+	}
+	rw.Header().Set("ETag", etag(user))
+	respond(rw, http.StatusOK, user)
+}
+
+func (h *Handler) getUser(rw http.ResponseWriter, req *http.Request, id string) {
+	user, err := h.store.Get(req.Context(), id)
+	if err != nil {
+		respondStoreError(rw, err)
+		return
+	}
+	tag := etag(user)
+	rw.Header().Set("ETag", tag)
+	if matchesETag(req.Header.Get("If-None-Match"), tag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	respond(rw, http.StatusOK, user)
+}
+
+// etag returns the ETag of the user, which changes each time it's updated.
+func etag(user restuser.User) string {
+	return `"` + user.UpdatedAt + `"`
+}
+
+// matchesETag reports whether the If-None-Match header matches the ETag, using the weak comparison.
+func matchesETag(ifNoneMatch, tag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesStrongETag reports whether the If-Match header matches the ETag, using the strong comparison,
+// so weak ETags never match.
+func matchesStrongETag(ifMatch, tag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) deleteUser(rw http.ResponseWriter, req *http.Request, id string) {
+	if err := h.store.Delete(req.Context(), id); err != nil {
+		respondStoreError(rw, err)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) listUsers(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	params := restuser.ListUsersParams{
+		Countries:  query["country"],
+		Cursor:     query.Get("cursor"),
+		IDs:        query["id"],
+		Email:      query.Get("email"),
+		Name:       query.Get("name"),
+		NamePrefix: query.Get("name_prefix"),
+		FirstName:  query.Get("first_name"),
+		LastName:   query.Get("last_name"),
+		Query:      query.Get("q"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if params.Limit, err = strconv.Atoi(limit); err != nil || params.Limit < 0 {
+			respondError(rw, http.StatusBadRequest, "limit should be a non-negative integer")
+			return
+		}
+	}
+	for key, value := range map[string]*time.Time{
+		"created_after":  &params.CreatedAfter,
+		"created_before": &params.CreatedBefore,
+		"updated_after":  &params.UpdatedAfter,
+	} {
+		if raw := query.Get(key); raw != "" {
+			var err error
+			if *value, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+				respondError(rw, http.StatusBadRequest, fmt.Sprintf("%s should be an RFC3339 time", key))
+				return
+			}
+		}
+	}
+	if !params.CreatedAfter.IsZero() && !params.CreatedBefore.IsZero() && !params.CreatedAfter.Before(params.CreatedBefore) {
+		respondError(rw, http.StatusBadRequest, "created_after should be before created_before")
+		return
+	}
+	var err error
+	if params.Sort, err = parseSort(query.Get("sort")); err != nil {
+		respondError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.Fields, err = parseFields(query.Get("fields")); err != nil {
+		respondError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.store.List(req.Context(), params)
+	if err != nil {
+		respondStoreError(rw, err)
+		return
+	}
+	users := make([]interface{}, len(page.Users))
+	for i, user := range page.Users {
+		if len(params.Fields) > 0 {
+			users[i] = sparse(user, params.Fields)
+			continue
+		}
+		user.PasswordHash = ""
+		user.PasswordSalt = ""
+		users[i] = user
+	}
+	if page.NextCursor != "" {
+		// keep the filters of the current page
+		next := req.URL.Query()
+		next.Set("limit", strconv.Itoa(params.Limit))
+		next.Set("cursor", page.NextCursor)
+		rw.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, h.basePath, usersPath, next.Encode()))
+	}
+	respond(rw, http.StatusOK, users)
+}
+
+// validateCreate checks the user to be created.
+func validateCreate(user restuser.User) error {
+	if user.ID != "" {
+		return errors.New("id should be empty")
+	}
+	return validatePassword(user.Password)
+}
+
+// prepareCreate sets the fields set by the service on the user to be created.
+func (h *Handler) prepareCreate(user *restuser.User) {
+	user.ID = newUUID()
+	user.CreatedAt = h.timestamp()
+	user.UpdatedAt = user.CreatedAt
+	setPassword(user)
+}
+
+// timestamp returns the current time formatted as RFC3339.
+// Nanoseconds are included so consecutive updates have different UpdatedAt values.
+func (h *Handler) timestamp() string {
+	return h.now().UTC().Format(time.RFC3339Nano)
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password should be at least %d characters long", minPasswordLength)
+	}
+	return nil
+}
+
+// setPassword generates a new random salt and sets the SHA-256 hash of the password concatenated with it.
+// The password is cleared, so it's never stored nor responded.
+func setPassword(user *restuser.User) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(fmt.Errorf("can't generate salt: %w", err))
+	}
+	user.PasswordSalt = hex.EncodeToString(salt)
+	hash := sha256.Sum256([]byte(user.Password + user.PasswordSalt))
+	user.PasswordHash = hex.EncodeToString(hash[:])
+	user.Password = ""
+}
+
+// newUUID generates a random (version 4) UUID.
+func newUUID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(fmt.Errorf("can't generate uuid: %w", err))
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+func respondStoreError(rw http.ResponseWriter, err error) {
+	status, message := storeErrorStatus(err)
+	respondError(rw, status, message)
+}
+
+// storeErrorStatus returns the status code and the message to respond for an error returned by the Store.
+func storeErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, restuser.ErrNotFound):
+		return http.StatusNotFound, "user not found"
+	case errors.Is(err, restuser.ErrConflict):
+		return http.StatusConflict, "user was modified concurrently"
+	case errors.Is(err, restuser.ErrBadRequest):
+		return http.StatusBadRequest, "invalid request"
+	default:
+		return http.StatusInternalServerError, "internal error"
+	}
+}
+
+func methodNotAllowed(rw http.ResponseWriter, allowed ...string) {
+	rw.Header().Set("Allow", strings.Join(allowed, ", "))
+	respondError(rw, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func respond(rw http.ResponseWriter, status int, payload interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(payload)
+}
+
+func respondError(rw http.ResponseWriter, status int, message string) {
+	respond(rw, status, restuser.ErrorResponse{Message: message})
+}
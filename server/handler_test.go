@@ -0,0 +1,338 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	someTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	handler := server.NewHandler(server.NewMemoryStore(), server.WithClock(func() time.Time { return someTime }))
+
+	t.Run("create stamps dates and hashes password", func(t *testing.T) {
+		rec := serve(handler, http.MethodPost, "/v1/users", `{"name":"pepe","password":"password123"}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		var user restuser.User
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&user))
+		assert.Equal(t, "2006-01-02T15:04:05Z", user.CreatedAt)
+		assert.Equal(t, user.CreatedAt, user.UpdatedAt)
+		assert.Empty(t, user.Password)
+		assert.Len(t, user.PasswordSalt, 32)
+		assert.Len(t, user.PasswordHash, 64)
+	})
+
+	for _, tc := range []struct {
+		name            string
+		method          string
+		url             string
+		body            string
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:            "create with id",
+			method:          http.MethodPost,
+			url:             "/v1/users",
+			body:            `{"id":"foo","password":"password123"}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "id should be empty",
+		},
+		{
+			name:            "create with short password",
+			method:          http.MethodPost,
+			url:             "/v1/users",
+			body:            `{"password":"1234567"}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "password should be at least 8 characters long",
+		},
+		{
+			name:           "create with invalid JSON",
+			method:         http.MethodPost,
+			url:            "/v1/users",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:            "update with different id",
+			method:          http.MethodPut,
+			url:             "/v1/users/foo",
+			body:            `{"id":"bar"}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "id can't be updated",
+		},
+		{
+			name:            "update not found",
+			method:          http.MethodPut,
+			url:             "/v1/users/foo",
+			body:            `{"id":"foo"}`,
+			expectedStatus:  http.StatusNotFound,
+			expectedMessage: "user not found",
+		},
+		{
+			name:            "list with invalid limit",
+			method:          http.MethodGet,
+			url:             "/v1/users?limit=foo",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "limit should be a non-negative integer",
+		},
+		{
+			name:            "list with invalid time",
+			method:          http.MethodGet,
+			url:             "/v1/users?created_after=yesterday",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "created_after should be an RFC3339 time",
+		},
+		{
+			name:            "list with empty time range",
+			method:          http.MethodGet,
+			url:             "/v1/users?created_after=2006-01-02T15:04:05Z&created_before=2006-01-02T15:04:05Z",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "created_after should be before created_before",
+		},
+		{
+			name:            "list sorted by unknown field",
+			method:          http.MethodGet,
+			url:             "/v1/users?sort=-password_hash",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `can't sort by unknown field "password_hash"`,
+		},
+		{
+			name:            "list with unknown field",
+			method:          http.MethodGet,
+			url:             "/v1/users?fields=id,password",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: `unknown field "password"`,
+		},
+		{
+			name:            "list sorted with invalid cursor",
+			method:          http.MethodGet,
+			url:             "/v1/users?sort=name&cursor=foo",
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "invalid request",
+		},
+		{
+			name:            "batch with too many items",
+			method:          http.MethodPost,
+			url:             "/v1/users:batchGet",
+			body:            `{"ids":[` + strings.Repeat(`"foo",`, 100) + `"foo"]}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "batch should have at most 100 items",
+		},
+		{
+			name:           "unknown batch operation",
+			method:         http.MethodPost,
+			url:            "/v1/users:batchUpdate",
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "batch method not allowed",
+			method:         http.MethodGet,
+			url:            "/v1/users:batchGet",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "patch without merge patch content type",
+			method:         http.MethodPatch,
+			url:            "/v1/users/foo",
+			body:           `{"name":"pepe"}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "unknown path",
+			method:         http.MethodGet,
+			url:            "/v2/users",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPatch,
+			url:            "/v1/users",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(handler, tc.method, tc.url, tc.body)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+
+			var errResp restuser.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+			if tc.expectedMessage != "" {
+				assert.Equal(t, tc.expectedMessage, errResp.Message)
+			}
+		})
+	}
+}
+
+func TestHandler_PatchUser(t *testing.T) {
+	handler := server.NewHandler(server.NewMemoryStore())
+	rec := serve(handler, http.MethodPost, "/v1/users", `{"name":"pepe","country":"es","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created restuser.User
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/v1/users/"+created.ID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("null clears fields", func(t *testing.T) {
+		rec := patch(`{"country":null}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var patched restuser.User
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&patched))
+		assert.Empty(t, patched.Country)
+		assert.Equal(t, "pepe", patched.Name)
+		assert.Equal(t, `"`+patched.UpdatedAt+`"`, rec.Header().Get("ETag"))
+
+		// If-Match uses the strong comparison, so weak ETags never match
+		req := httptest.NewRequest(http.MethodPatch, "/v1/users/"+created.ID, strings.NewReader(`{"name":"pepito"}`))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", "W/"+rec.Header().Get("ETag"))
+		weak := httptest.NewRecorder()
+		handler.ServeHTTP(weak, req)
+		assert.Equal(t, http.StatusPreconditionFailed, weak.Code)
+	})
+
+	for _, tc := range []struct {
+		body            string
+		expectedMessage string
+	}{
+		{body: `{"id":"foo"}`, expectedMessage: `field "id" can't be patched`},
+		{body: `{"password":null}`, expectedMessage: "password can't be cleared"},
+		{body: `{"password":"short"}`, expectedMessage: "password should be at least 8 characters long"},
+		{body: `{"name":1}`},
+	} {
+		t.Run(tc.body, func(t *testing.T) {
+			rec := patch(tc.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var errResp restuser.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&errResp))
+			if tc.expectedMessage != "" {
+				assert.Equal(t, tc.expectedMessage, errResp.Message)
+			}
+		})
+	}
+}
+
+func TestHandler_PatchUser_ConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := &conflictingStore{MemoryStore: server.NewMemoryStore()}
+	srv := httptest.NewServer(server.NewHandler(store))
+	defer srv.Close()
+	api := restuser.New(restuser.Config{URL: srv.URL})
+
+	created, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+
+	t.Run("applies the patch again without If-Match", func(t *testing.T) {
+		store.setConflicts(2)
+		patched, err := api.PatchUser(ctx, created.ID, restuser.UserPatch{Name: restuser.String("pepito")}, "")
+		require.NoError(t, err)
+		assert.Equal(t, "pepito", patched.Name)
+	})
+
+	t.Run("conflicts when always updated concurrently", func(t *testing.T) {
+		store.setConflicts(-1)
+		_, err := api.PatchUser(ctx, created.ID, restuser.UserPatch{Name: restuser.String("paco")}, "")
+		assert.True(t, restuser.IsConflict(err), "expected conflict, got %v", err)
+	})
+
+	t.Run("precondition fails with If-Match", func(t *testing.T) {
+		store.setConflicts(1)
+		current, err := api.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		_, err = api.PatchUser(ctx, created.ID, restuser.UserPatch{Name: restuser.String("paco")}, current.UpdatedAt)
+		assert.True(t, restuser.IsPreconditionFailed(err), "expected precondition failed, got %v", err)
+	})
+}
+
+func TestMemoryStore_List_UnknownSortField(t *testing.T) {
+	store := server.NewMemoryStore()
+	_, err := store.List(context.Background(), restuser.ListUsersParams{Sort: []restuser.SortKey{{Field: "password"}}})
+	assert.True(t, restuser.IsBadRequest(err), "expected bad request, got %v", err)
+}
+
+func TestHandler_StoreFailure(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(failingStore{}))
+	defer srv.Close()
+	api := restuser.New(restuser.Config{URL: srv.URL})
+
+	_, err := api.GetUser(context.Background(), "foo")
+	assert.Equal(t, restuser.Error{StatusCode: http.StatusInternalServerError, Response: &restuser.ErrorResponse{Message: "internal error"}}, err)
+
+	_, err = api.ListUsers(context.Background(), restuser.ListUsersParams{})
+	assert.True(t, restuser.IsInternal(err))
+}
+
+func TestWithBasePath(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(server.NewMemoryStore(), server.WithBasePath("/preproduction/v1")))
+	defer srv.Close()
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithBasePath("/preproduction/v1"))
+
+	users, err := api.ListUsers(context.Background(), restuser.ListUsersParams{})
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func serve(handler http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return rec
+}
+
+type failingStore struct{}
+
+func (failingStore) Create(context.Context, restuser.User) error { return errors.New("failed") }
+func (failingStore) Update(context.Context, restuser.User, string) error {
+	return errors.New("failed")
+}
+func (failingStore) Get(context.Context, string) (restuser.User, error) {
+	return restuser.User{}, errors.New("failed")
+}
+func (failingStore) Delete(context.Context, string) error { return errors.New("failed") }
+func (failingStore) List(context.Context, restuser.ListUsersParams) (*restuser.UsersPage, error) {
+	return nil, errors.New("failed")
+}
+
+// conflictingStore fails the given amount of updates as if the users were updated concurrently,
+// or all of them if conflicts is negative.
+type conflictingStore struct {
+	*server.MemoryStore
+	mu        sync.Mutex
+	conflicts int
+}
+
+func (s *conflictingStore) setConflicts(conflicts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conflicts = conflicts
+}
+
+func (s *conflictingStore) Update(ctx context.Context, user restuser.User, updatedAt string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conflicts == 0 {
+		return s.MemoryStore.Update(ctx, user, updatedAt)
+	}
+	if s.conflicts > 0 {
+		s.conflicts--
+	}
+	return restuser.ErrConflict
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// patchableFields are the fields of the user that can be modified by a merge patch, by their JSON name.
+var patchableFields = map[string]func(*restuser.User) *string{
+	"first_name": func(u *restuser.User) *string { return &u.FirstName },
+	"last_name":  func(u *restuser.User) *string { return &u.LastName },
+	"name":       func(u *restuser.User) *string { return &u.Name },
+	"email":      func(u *restuser.User) *string { return &u.Email },
+	"password":   func(u *restuser.User) *string { return &u.Password },
+	"country":    func(u *restuser.User) *string { return &u.Country },
+}
+
+// patchAttempts is the maximum amount of times a patch without If-Match is applied when the user is updated concurrently.
+const patchAttempts = 3
+
+func (h *Handler) patchUser(rw http.ResponseWriter, req *http.Request, id string) {
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != mergePatchContentType {
+		respondError(rw, http.StatusUnsupportedMediaType, fmt.Sprintf("body should be provided as %s", mergePatchContentType))
+		return
+	}
+	var patch map[string]*string
+	if err := json.NewDecoder(req.Body).Decode(&patch); err != nil {
+		respondError(rw, http.StatusBadRequest, fmt.Sprintf("invalid patch JSON: %s", err))
+		return
+	}
+
+	ifMatch := req.Header.Get("If-Match")
+	for attempt := 1; ; attempt++ {
+		stored, err := h.store.Get(req.Context(), id)
+		if err != nil {
+			respondStoreError(rw, err)
+			return
+		}
+		if ifMatch != "" && !matchesStrongETag(ifMatch, etag(stored)) {
+			respondError(rw, http.StatusPreconditionFailed, "user was updated since it was retrieved")
+			return
+		}
+
+		user, err := applyPatch(stored, patch)
+		if err != nil {
+			respondError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+		user.UpdatedAt = h.timestamp()
+		err = h.store.Update(req.Context(), user, stored.UpdatedAt)
+		switch {
+		case errors.Is(err, restuser.ErrConflict) && ifMatch != "":
+			// modified concurrently since the precondition was checked, so it doesn't hold anymore
+			respondError(rw, http.StatusPreconditionFailed, "user was updated since it was retrieved")
+			return
+		case errors.Is(err, restuser.ErrConflict) && attempt < patchAttempts:
+			// the patch is applied again to the user just updated
+			continue
+		case err != nil:
+			respondStoreError(rw, err)
+			return
+		}
+		rw.Header().Set("ETag", etag(user))
+		respond(rw, http.StatusOK, user)
+		return
+	}
+}
+
+// applyPatch returns the user modified by the merge patch, or an error explaining why the patch is invalid.
+func applyPatch(user restuser.User, patch map[string]*string) (restuser.User, error) {
+	for name, value := range patch {
+		field, ok := patchableFields[name]
+		if !ok {
+			return user, fmt.Errorf("field %q can't be patched", name)
+		}
+		// null clears the field, as defined by RFC 7396
+		if value == nil {
+			value = new(string)
+		}
+		*field(&user) = *value
+	}
+	if password, ok := patch["password"]; ok {
+		if password == nil || *password == "" {
+			return user, errors.New("password can't be cleared")
+		}
+		if err := validatePassword(*password); err != nil {
+			return user, err
+		}
+		setPassword(&user)
+	}
+	return user, nil
+}
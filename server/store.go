@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+)
+
+// Store persists the users.
+// Methods should return errors matching restuser.ErrNotFound, restuser.ErrConflict and restuser.ErrBadRequest
+// through errors.Is when appropriate, any other error will be responded as an internal error.
+type Store interface {
+	// Create stores a new user, its ID was already generated.
+	Create(ctx context.Context, user restuser.User) error
+	// Update replaces the user with the same ID, only if its UpdatedAt equals expectedUpdatedAt,
+	// otherwise it returns restuser.ErrConflict.
+	Update(ctx context.Context, user restuser.User, expectedUpdatedAt string) error
+	// Get retrieves the user with the given ID.
+	Get(ctx context.Context, id string) (restuser.User, error)
+	// Delete removes the user with the given ID.
+	Delete(ctx context.Context, id string) error
+	// List retrieves a page of users matching the params.
+	// The cursor returned is opaque to the caller, it will be provided in the params to retrieve the next page.
+	List(ctx context.Context, params restuser.ListUsersParams) (*restuser.UsersPage, error)
+}
+
+// MemoryStore is a Store that keeps the users in memory.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	users map[string]restuser.User
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{users: map[string]restuser.User{}}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(_ context.Context, user restuser.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user.ID]; ok {
+		return restuser.ErrConflict
+	}
+	s.users[user.ID] = user
+	return nil
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(_ context.Context, user restuser.User, expectedUpdatedAt string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return restuser.ErrNotFound
+	}
+	if stored.UpdatedAt != expectedUpdatedAt {
+		return restuser.ErrConflict
+	}
+	s.users[user.ID] = user
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (restuser.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[id]
+	if !ok {
+		return restuser.User{}, restuser.ErrNotFound
+	}
+	return user, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return restuser.ErrNotFound
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// List implements Store.
+// Users are sorted by ID unless params.Sort is provided, in which case ties are sorted by ID.
+// The cursor of each page is the ID of its last user, or its sorted fields followed by its ID when params.Sort is provided.
+// Sorting by unknown fields fails with restuser.ErrBadRequest.
+func (s *MemoryStore) List(_ context.Context, params restuser.ListUsersParams) (*restuser.UsersPage, error) {
+	for _, key := range params.Sort {
+		if !contains(listedFields, key.Field) {
+			return nil, fmt.Errorf("can't sort by unknown field %q: %w", key.Field, restuser.ErrBadRequest)
+		}
+	}
+	keys := append(append([]restuser.SortKey(nil), params.Sort...), restuser.SortKey{Field: "id"})
+	var after []string
+	if params.Cursor != "" {
+		var err error
+		if after, err = decodeCursor(params.Cursor, len(keys)); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	countries := params.Countries
+	if params.Country != "" {
+		countries = append([]string{params.Country}, countries...)
+	}
+	users := []restuser.User{}
+	for _, user := range s.users {
+		if len(countries) > 0 && !contains(countries, user.Country) {
+			continue
+		}
+		if len(params.IDs) > 0 && !contains(params.IDs, user.ID) {
+			continue
+		}
+		if !matches(user, params) {
+			continue
+		}
+		if after != nil && compare(keys, sortValues(user, keys), after) <= 0 {
+			continue
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return compare(keys, sortValues(users[i], keys), sortValues(users[j], keys)) < 0
+	})
+
+	page := &restuser.UsersPage{Users: users}
+	if params.Limit > 0 && len(users) > params.Limit {
+		page.Users = users[:params.Limit]
+		page.NextCursor = encodeCursor(sortValues(page.Users[params.Limit-1], keys))
+	}
+	return page, nil
+}
+
+// sortValues returns the values of the user fields to sort by.
+func sortValues(user restuser.User, keys []restuser.SortKey) []string {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = field(user, key.Field)
+	}
+	return values
+}
+
+// compare compares the values of the fields to sort by, returning a negative number if a goes before b.
+func compare(keys []restuser.SortKey, a, b []string) int {
+	for i, key := range keys {
+		c := strings.Compare(a[i], b[i])
+		if key.Field == "created_at" || key.Field == "updated_at" {
+			// RFC3339 timestamps with fractional seconds don't sort lexicographically
+			c = compareTimes(parseTime(a[i]), parseTime(b[i]))
+		}
+		if key.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// encodeCursor encodes the values to sort by of the last user of a page.
+// A single value, the ID when the users are sorted by ID, is used as is, to keep the cursors short.
+func encodeCursor(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string, length int) ([]string, error) {
+	if length == 1 {
+		return []string{cursor}, nil
+	}
+	var values []string
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil || len(values) != length {
+		return nil, fmt.Errorf("invalid cursor %q: %w", cursor, restuser.ErrBadRequest)
+	}
+	return values, nil
+}
+
+// matches reports whether the user matches the email, name, free-text and time filters of the params.
+func matches(user restuser.User, params restuser.ListUsersParams) bool {
+	for _, filter := range []struct{ value, expected string }{
+		{user.Email, params.Email},
+		{user.Name, params.Name},
+		{user.FirstName, params.FirstName},
+		{user.LastName, params.LastName},
+	} {
+		if filter.expected != "" && filter.value != filter.expected {
+			return false
+		}
+	}
+	if !strings.HasPrefix(user.Name, params.NamePrefix) {
+		return false
+	}
+	if !params.CreatedAfter.IsZero() && !parseTime(user.CreatedAt).After(params.CreatedAfter) {
+		return false
+	}
+	if !params.CreatedBefore.IsZero() && !parseTime(user.CreatedAt).Before(params.CreatedBefore) {
+		return false
+	}
+	if !params.UpdatedAfter.IsZero() && !parseTime(user.UpdatedAt).After(params.UpdatedAfter) {
+		return false
+	}
+	if params.Query != "" {
+		query := strings.ToLower(params.Query)
+		for _, value := range []string{user.Name, user.FirstName, user.LastName, user.Email} {
+			if strings.Contains(strings.ToLower(value), query) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// parseTime parses the RFC3339 timestamps set by the Handler, the zero time is returned if it can't be parsed.
+func parseTime(timestamp string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, timestamp)
+	return t
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package restuser
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// UserService is implemented by API, and allows replacing or decorating it.
+type UserService interface {
+	CreateUser(ctx context.Context, user *User) (*User, error)
+	UpdateUser(ctx context.Context, user *User) (*User, error)
+	GetUser(ctx context.Context, id string) (*User, error)
+	DeleteUser(ctx context.Context, id string) error
+	ListUsers(ctx context.Context, params ListUsersParams) ([]User, error)
+}
+
+var _ UserService = (*API)(nil)
+
+// Decorator wraps a UserService adding some functionality to it.
+type Decorator func(UserService) UserService
+
+// Decorate applies the decorators to the service.
+// The first decorator provided is the outermost one, so it's the first one to be called.
+func Decorate(svc UserService, decorators ...Decorator) UserService {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		svc = decorators[i](svc)
+	}
+	return svc
+}
+
+// LoggingDecorator logs each call with its duration and error, if any, using the provided printf-like function.
+// log.Printf or testing.T.Logf can be used, for instance.
+func LoggingDecorator(logf func(format string, args ...interface{})) Decorator {
+	return func(next UserService) UserService {
+		return &observedService{next: next, observe: func(method string, duration time.Duration, err error) {
+			if err != nil {
+				logf("restuser: %s failed after %s: %s", method, duration, err)
+				return
+			}
+			logf("restuser: %s succeeded after %s", method, duration)
+		}}
+	}
+}
+
+// MetricsObserver is called after each call with the name of the method, its duration and its error, if any.
+type MetricsObserver func(method string, duration time.Duration, err error)
+
+// MetricsDecorator reports the duration and the result of each call to the observer.
+func MetricsDecorator(observe MetricsObserver) Decorator {
+	return func(next UserService) UserService {
+		return &observedService{next: next, observe: observe}
+	}
+}
+
+type observedService struct {
+	next    UserService
+	observe MetricsObserver
+}
+
+func (s *observedService) CreateUser(ctx context.Context, user *User) (*User, error) {
+	start := time.Now()
+	res, err := s.next.CreateUser(ctx, user)
+	s.observe("CreateUser", time.Since(start), err)
+	return res, err
+}
+
+func (s *observedService) UpdateUser(ctx context.Context, user *User) (*User, error) {
+	start := time.Now()
+	res, err := s.next.UpdateUser(ctx, user)
+	s.observe("UpdateUser", time.Since(start), err)
+	return res, err
+}
+
+func (s *observedService) GetUser(ctx context.Context, id string) (*User, error) {
+	start := time.Now()
+	res, err := s.next.GetUser(ctx, id)
+	s.observe("GetUser", time.Since(start), err)
+	return res, err
+}
+
+func (s *observedService) DeleteUser(ctx context.Context, id string) error {
+	start := time.Now()
+	err := s.next.DeleteUser(ctx, id)
+	s.observe("DeleteUser", time.Since(start), err)
+	return err
+}
+
+func (s *observedService) ListUsers(ctx context.Context, params ListUsersParams) ([]User, error) {
+	start := time.Now()
+	res, err := s.next.ListUsers(ctx, params)
+	s.observe("ListUsers", time.Since(start), err)
+	return res, err
+}
+
+// CachingDecorator caches the users retrieved by GetUser for the given TTL, keeping at most maxEntries users,
+// or unlimited if maxEntries is not positive.
+// Users created through the decorated service are cached too, and updated or deleted ones are evicted.
+// Changes performed by other clients are not seen until the cached entry expires.
+func CachingDecorator(ttl time.Duration, maxEntries int) Decorator {
+	return func(next UserService) UserService {
+		return &cachingService{
+			next:       next,
+			ttl:        ttl,
+			maxEntries: maxEntries,
+			entries:    map[string]cacheEntry{},
+		}
+	}
+}
+
+type cachingService struct {
+	next       UserService
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// fetches tracks the users being retrieved, so they're not stored if they're evicted meanwhile.
+	fetches fetchTracker
+}
+
+type cacheEntry struct {
+	user    User
+	expires time.Time
+}
+
+func (s *cachingService) CreateUser(ctx context.Context, user *User) (*User, error) {
+	res, err := s.next.CreateUser(ctx, user)
+	if err == nil {
+		s.store(res)
+	}
+	return res, err
+}
+
+func (s *cachingService) UpdateUser(ctx context.Context, user *User) (*User, error) {
+	if user != nil {
+		// evicted after the update, so users retrieved concurrently are not stored in the cache
+		defer s.evict(user.ID)
+	}
+	return s.next.UpdateUser(ctx, user)
+}
+
+func (s *cachingService) GetUser(ctx context.Context, id string) (*User, error) {
+	if user, ok := s.load(id); ok {
+		return user, nil
+	}
+	generation := s.fetches.start(id)
+	res, err := s.next.GetUser(ctx, id)
+	if s.fetches.finish(id, generation) && err == nil {
+		s.store(res)
+	}
+	return res, err
+}
+
+func (s *cachingService) DeleteUser(ctx context.Context, id string) error {
+	defer s.evict(id)
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *cachingService) ListUsers(ctx context.Context, params ListUsersParams) ([]User, error) {
+	return s.next.ListUsers(ctx, params)
+}
+
+func (s *cachingService) load(id string) (*User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[id]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	user := entry.user
+	return &user, true
+}
+
+func (s *cachingService) store(user *User) {
+	if user == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if _, ok := s.entries[user.ID]; !ok && s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		for id, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, id)
+			}
+		}
+		// still full, evict any entry
+		for id := range s.entries {
+			if len(s.entries) < s.maxEntries {
+				break
+			}
+			delete(s.entries, id)
+		}
+	}
+	s.entries[user.ID] = cacheEntry{user: *user, expires: now.Add(s.ttl)}
+}
+
+func (s *cachingService) evict(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	s.fetches.evict(id)
+}
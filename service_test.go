@@ -0,0 +1,235 @@
+package restuser_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecorate(t *testing.T) {
+	var calls []string
+	recorder := func(name string) restuser.Decorator {
+		return restuser.MetricsDecorator(func(method string, _ time.Duration, _ error) {
+			calls = append(calls, name+":"+method)
+		})
+	}
+
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	svc := restuser.Decorate(srv.API(), recorder("outer"), recorder("inner"))
+	_, _ = svc.GetUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+
+	// inner one finishes first
+	assert.Equal(t, []string{"inner:GetUser", "outer:GetUser"}, calls)
+}
+
+func TestLoggingDecorator(t *testing.T) {
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var logs []string
+	svc := restuser.Decorate(srv.API(), restuser.LoggingDecorator(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}))
+
+	_, err := svc.CreateUser(context.Background(), &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+	_, err = svc.GetUser(context.Background(), "c3e11b46-109c-11eb-adc1-0242ac120002")
+	require.Error(t, err)
+
+	require.Len(t, logs, 2)
+	assert.Contains(t, logs[0], "restuser: CreateUser succeeded after")
+	assert.Contains(t, logs[1], "restuser: GetUser failed after")
+	assert.Contains(t, logs[1], "userservice responded 404")
+}
+
+func TestMetricsDecorator(t *testing.T) {
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	errs := map[string]error{}
+	svc := restuser.Decorate(srv.API(), restuser.MetricsDecorator(func(method string, duration time.Duration, err error) {
+		assert.True(t, duration > 0)
+		errs[method] = err
+	}))
+
+	ctx := context.Background()
+	user, err := svc.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+	require.NoError(t, err)
+	_, _ = svc.UpdateUser(ctx, user)
+	_, _ = svc.ListUsers(ctx, restuser.ListUsersParams{})
+	_ = svc.DeleteUser(ctx, user.ID)
+	_, _ = svc.GetUser(ctx, user.ID)
+
+	assert.Len(t, errs, 5)
+	assert.NoError(t, errs["CreateUser"])
+	assert.NoError(t, errs["UpdateUser"])
+	assert.NoError(t, errs["ListUsers"])
+	assert.NoError(t, errs["DeleteUser"])
+	assert.True(t, restuser.IsNotFound(errs["GetUser"]))
+}
+
+func TestCachingDecorator(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+
+	var backendCalls int
+	counter := restuser.MetricsDecorator(func(string, time.Duration, error) { backendCalls++ })
+
+	t.Run("caches retrieved users", func(t *testing.T) {
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+
+		backendCalls = 0
+		svc := restuser.Decorate(srv.API(), restuser.CachingDecorator(time.Minute, 10), counter)
+		for i := 0; i < 3; i++ {
+			got, err := svc.GetUser(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user, got)
+		}
+		assert.Equal(t, 1, backendCalls)
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+
+		backendCalls = 0
+		svc := restuser.Decorate(srv.API(), restuser.CachingDecorator(time.Nanosecond, 10), counter)
+		_, _ = svc.GetUser(ctx, user.ID)
+		time.Sleep(time.Millisecond)
+		_, _ = svc.GetUser(ctx, user.ID)
+		assert.Equal(t, 2, backendCalls)
+	})
+
+	t.Run("updated and deleted users are evicted", func(t *testing.T) {
+		svc := restuser.Decorate(srv.API(), restuser.CachingDecorator(time.Minute, 10), counter)
+		user, err := svc.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+
+		user.Name = "pepito"
+		updated, err := svc.UpdateUser(ctx, user)
+		require.NoError(t, err)
+
+		backendCalls = 0
+		for i := 0; i < 2; i++ {
+			got, err := svc.GetUser(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, updated, got)
+		}
+		assert.Equal(t, 1, backendCalls)
+
+		require.NoError(t, svc.DeleteUser(ctx, user.ID))
+		_, err = svc.GetUser(ctx, user.ID)
+		assert.True(t, restuser.IsNotFound(err))
+	})
+
+	t.Run("users retrieved during an update or delete are evicted", func(t *testing.T) {
+		var svc restuser.UserService
+		// racing retrieves the user through the cache while it's being modified
+		racing := func(next restuser.UserService) restuser.UserService {
+			return racingService{UserService: next, race: func(id string) {
+				_, err := svc.GetUser(ctx, id)
+				require.NoError(t, err)
+			}}
+		}
+		svc = restuser.Decorate(srv.API(), restuser.CachingDecorator(time.Minute, 10), racing)
+		user, err := svc.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+
+		user.Name = "pepito"
+		updated, err := svc.UpdateUser(ctx, user)
+		require.NoError(t, err)
+		got, err := svc.GetUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updated, got)
+
+		require.NoError(t, svc.DeleteUser(ctx, user.ID))
+		_, err = svc.GetUser(ctx, user.ID)
+		assert.True(t, restuser.IsNotFound(err))
+	})
+
+	t.Run("users retrieved before an update and responded after it are not cached", func(t *testing.T) {
+		user, err := srv.API().CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123"})
+		require.NoError(t, err)
+
+		fetched, release := make(chan struct{}, 1), make(chan struct{})
+		slow := func(next restuser.UserService) restuser.UserService {
+			return slowService{UserService: next, fetched: fetched, release: release}
+		}
+		svc := restuser.Decorate(srv.API(), restuser.CachingDecorator(time.Minute, 10), slow)
+
+		stale := make(chan *restuser.User)
+		go func() {
+			got, _ := svc.GetUser(ctx, user.ID)
+			stale <- got
+		}()
+		<-fetched
+		user.Name = "pepito"
+		updated, err := srv.API().UpdateUser(ctx, user)
+		require.NoError(t, err)
+		_, err = svc.UpdateUser(ctx, updated)
+		require.NoError(t, err)
+		close(release)
+		assert.Equal(t, "pepe", (<-stale).Name)
+
+		got, err := svc.GetUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "pepito", got.Name)
+	})
+
+	t.Run("keeps at most max entries", func(t *testing.T) {
+		svc := restuser.Decorate(srv.API(), restuser.CachingDecorator(time.Minute, 1), counter)
+		first, err := svc.CreateUser(ctx, &restuser.User{Name: "first", Password: "password123"})
+		require.NoError(t, err)
+		_, err = svc.CreateUser(ctx, &restuser.User{Name: "second", Password: "password123"})
+		require.NoError(t, err)
+
+		backendCalls = 0
+		_, err = svc.GetUser(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, backendCalls)
+	})
+}
+
+// racingService calls race with the ID of the users being updated or deleted, before doing so.
+type racingService struct {
+	restuser.UserService
+	race func(id string)
+}
+
+func (s racingService) UpdateUser(ctx context.Context, user *restuser.User) (*restuser.User, error) {
+	s.race(user.ID)
+	return s.UserService.UpdateUser(ctx, user)
+}
+
+func (s racingService) DeleteUser(ctx context.Context, id string) error {
+	s.race(id)
+	return s.UserService.DeleteUser(ctx, id)
+}
+
+// slowService holds the users retrieved until release is closed, notifying fetched once they're retrieved.
+// Calls finding fetched full are not held.
+type slowService struct {
+	restuser.UserService
+	fetched chan struct{}
+	release chan struct{}
+}
+
+func (s slowService) GetUser(ctx context.Context, id string) (*restuser.User, error) {
+	user, err := s.UserService.GetUser(ctx, id)
+	select {
+	case s.fetched <- struct{}{}:
+		<-s.release
+	default:
+	}
+	return user, err
+}
@@ -0,0 +1,92 @@
+package restuser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StreamUsers calls fn with each one of the users matching params, in order.
+// Users are decoded one by one as the response is read, so the whole listing is never kept in memory.
+// All the pages are listed, params.Limit optionally configures the amount of users requested on each one.
+// If fn returns an error, the listing is stopped, the response body is closed and that error is returned.
+func (a *API) StreamUsers(ctx context.Context, params ListUsersParams, fn func(User) error) error {
+	if err := params.validate(); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	for {
+		next, err := a.streamUsersPage(ctx, params, fn)
+		if err != nil || next == "" {
+			return err
+		}
+		params.Cursor = next
+	}
+}
+
+// streamUsersPage calls fn with each user of a single page, returning the cursor of the next one.
+func (a *API) streamUsersPage(ctx context.Context, params ListUsersParams, fn func(User) error) (string, error) {
+	resp, err := a.doRequest(ctx, opListUsers, usersPath, params.query(), nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var callbackErr callbackError
+		if err := decodeUsers(resp.Body, fn); errors.As(err, &callbackErr) {
+			return "", callbackErr.err
+		} else if err != nil {
+			return "", fmt.Errorf("response was %d, however can't unmarshal users JSON: %w", resp.StatusCode, err)
+		}
+		return nextCursor(resp.Header), nil
+	case http.StatusBadRequest,
+		http.StatusInternalServerError:
+		return "", a.unmarshalErrorResponse(resp)
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return "", a.retryAfterError(resp)
+	default:
+		return "", a.unexpectedStatusError(resp)
+	}
+}
+
+// callbackError wraps the error returned by the callback of decodeUsers, to distinguish it from the decoding ones.
+type callbackError struct {
+	err error
+}
+
+func (e callbackError) Error() string {
+	return e.err.Error()
+}
+
+// decodeUsers decodes the JSON array of users read from r, calling fn with each one of them as soon as it's decoded.
+func decodeUsers(r io.Reader, fn func(User) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected an array, got %v", tok)
+	}
+	for dec.More() {
+		var user User
+		if err := dec.Decode(&user); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return callbackError{err}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return nil
+}
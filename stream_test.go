@@ -0,0 +1,135 @@
+package restuser_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-faceit-candidate/restuser"
+	"github.com/a-faceit-candidate/restuser/restusertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_StreamUsers(t *testing.T) {
+	ctx := context.Background()
+	srv := restusertest.NewServer()
+	defer srv.Close()
+	api := srv.API()
+
+	var created []string
+	for i := 0; i < 5; i++ {
+		user, err := api.CreateUser(ctx, &restuser.User{Name: fmt.Sprintf("user%d", i), Password: "password123", Country: "br"})
+		require.NoError(t, err)
+		created = append(created, user.ID)
+	}
+	_, err := api.CreateUser(ctx, &restuser.User{Name: "pepe", Password: "password123", Country: "es"})
+	require.NoError(t, err)
+
+	var streamed []string
+	err = api.StreamUsers(ctx, restuser.ListUsersParams{Country: "br", Limit: 2}, func(user restuser.User) error {
+		assert.Equal(t, "br", user.Country)
+		assert.Empty(t, user.PasswordHash)
+		streamed = append(streamed, user.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, created, streamed)
+}
+
+func TestAPI_StreamUsers_CallbackError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = io.WriteString(rw, `[{"id":"first","country":"br"},{"id":"second","country":"br"},`)
+		rw.(http.Flusher).Flush()
+		// the rest of the response never arrives
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+
+	closed := make(chan struct{})
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil {
+			resp.Body = closeNotifier{ReadCloser: resp.Body, closed: closed}
+		}
+		return resp, err
+	})}
+	api := restuser.New(restuser.Config{URL: srv.URL}, restuser.WithHTTPClient(client))
+
+	errStop := errors.New("stop")
+	var streamed []string
+	err := api.StreamUsers(context.Background(), restuser.ListUsersParams{}, func(user restuser.User) error {
+		streamed = append(streamed, user.ID)
+		if len(streamed) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, []string{"first", "second"}, streamed)
+	select {
+	case <-closed:
+	default:
+		t.Error("response body should be closed")
+	}
+}
+
+func TestAPI_StreamUsers_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "bad request",
+			status: http.StatusBadRequest,
+			body:   `{"message":"invalid"}`,
+			check:  func(t *testing.T, err error) { assert.True(t, restuser.IsBadRequest(err)) },
+		},
+		{
+			name:   "not an array",
+			status: http.StatusOK,
+			body:   `{"id":"first"}`,
+			check:  func(t *testing.T, err error) { assert.Error(t, err) },
+		},
+		{
+			name:   "truncated",
+			status: http.StatusOK,
+			body:   `[{"id":"first"},{"id":`,
+			check:  func(t *testing.T, err error) { assert.Error(t, err) },
+		},
+		{
+			name:   "null",
+			status: http.StatusOK,
+			body:   `null`,
+			check:  func(t *testing.T, err error) { assert.NoError(t, err) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tc.status)
+				_, _ = io.WriteString(rw, tc.body)
+			}))
+			defer srv.Close()
+
+			api := restuser.New(restuser.Config{URL: srv.URL})
+			err := api.StreamUsers(context.Background(), restuser.ListUsersParams{}, func(restuser.User) error { return nil })
+			tc.check(t, err)
+		})
+	}
+}
+
+type closeNotifier struct {
+	io.ReadCloser
+	closed chan struct{}
+}
+
+func (c closeNotifier) Close() error {
+	close(c.closed)
+	return c.ReadCloser.Close()
+}